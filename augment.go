@@ -0,0 +1,337 @@
+package gans
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+
+	"github.com/unixpickle/autofunc"
+	"github.com/unixpickle/num-analysis/linalg"
+	"github.com/unixpickle/serializer"
+	"github.com/unixpickle/sgd"
+	"github.com/unixpickle/weakai/neuralnet"
+)
+
+func init() {
+	var a Augmentation
+	serializer.RegisterTypedDeserializer(a.SerializerType(), DeserializeAugmentation)
+}
+
+// Augmentation configures random image augmentations.
+//
+// Every augmentation is a linear function of its input
+// plus a constant, so augmentations can be applied to
+// generated samples and differentiated through.
+//
+// A nil Augmentation leaves its inputs unchanged.
+type Augmentation struct {
+	// Width, Height, and Depth specify the dimensions of
+	// the image tensors.
+	Width  int
+	Height int
+	Depth  int
+
+	// FlipProb is the probability of flipping an image
+	// horizontally.
+	FlipProb float64
+
+	// MaxShift is the maximum number of pixels by which an
+	// image is translated in each direction.
+	// Pixels shifted in from outside the image are 0.
+	MaxShift int
+
+	// MinCropScale, if non-zero, enables random crops.
+	// Each crop's side lengths are a random fraction
+	// between MinCropScale and 1 of the image's, and the
+	// crop is scaled back to the full image size using
+	// nearest-neighbor sampling.
+	MinCropScale float64
+
+	// Brightness is the maximum amount added to or
+	// subtracted from every value in an image.
+	Brightness float64
+
+	// Contrast is the maximum relative change in the
+	// deviation of an image's values from their mean.
+	Contrast float64
+
+	// Saturation is the maximum relative change in the
+	// deviation of each pixel's channels from their mean.
+	// It has no effect on images whose depth is not 3.
+	Saturation float64
+}
+
+// DeserializeAugmentation deserializes an Augmentation.
+func DeserializeAugmentation(d []byte) (*Augmentation, error) {
+	var width, height, depth, shift serializer.Int
+	var flip, crop, brightness, contrast, saturation serializer.Float64
+	err := serializer.DeserializeAny(d, &width, &height, &depth, &flip, &shift,
+		&crop, &brightness, &contrast, &saturation)
+	if err != nil {
+		return nil, err
+	}
+	if width <= 0 || height <= 0 || depth <= 0 {
+		return nil, errors.New("augmentation dimensions must be positive")
+	}
+	return &Augmentation{
+		Width:        int(width),
+		Height:       int(height),
+		Depth:        int(depth),
+		FlipProb:     float64(flip),
+		MaxShift:     int(shift),
+		MinCropScale: float64(crop),
+		Brightness:   float64(brightness),
+		Contrast:     float64(contrast),
+		Saturation:   float64(saturation),
+	}, nil
+}
+
+// Tensor returns a randomly augmented copy of a tensor.
+// It panics if the tensor's dimensions do not match the
+// augmentation's.
+func (a *Augmentation) Tensor(t *neuralnet.Tensor3) *neuralnet.Tensor3 {
+	if a != nil && (t.Width != a.Width || t.Height != a.Height || t.Depth != a.Depth) {
+		panic(fmt.Sprintf("tensor is %dx%dx%d but augmentation expects %dx%dx%d",
+			t.Width, t.Height, t.Depth, a.Width, a.Height, a.Depth))
+	}
+	return &neuralnet.Tensor3{
+		Width:  t.Width,
+		Height: t.Height,
+		Depth:  t.Depth,
+		Data:   a.batchVec(t.Data, 1),
+	}
+}
+
+// Batch randomly augments every image in a batch of n
+// images, each of which is augmented differently.
+// The result can be back-propagated through.
+// It panics if the input is not n images of the
+// augmentation's dimensions.
+func (a *Augmentation) Batch(in autofunc.Result, n int) autofunc.Result {
+	if a == nil {
+		return in
+	}
+	if expected := n * a.Width * a.Height * a.Depth; len(in.Output()) != expected {
+		panic(fmt.Sprintf("expected %d images of size %dx%dx%d (%d values) but got %d values",
+			n, a.Width, a.Height, a.Depth, expected, len(in.Output())))
+	}
+	res := &augmentResult{
+		Input:   in,
+		Params:  make([]*augmentParams, n),
+		Augment: a,
+	}
+	imageSize := len(in.Output()) / n
+	for i := range res.Params {
+		res.Params[i] = a.randomParams()
+		image := in.Output()[i*imageSize : (i+1)*imageSize]
+		res.OutputVec = append(res.OutputVec, a.forward(res.Params[i], image)...)
+	}
+	return res
+}
+
+// SerializerType returns the unique ID used to serialize
+// an Augmentation with the serializer package.
+func (a *Augmentation) SerializerType() string {
+	return "github.com/unixpickle/gans.Augmentation"
+}
+
+// Serialize serializes the configuration.
+func (a *Augmentation) Serialize() ([]byte, error) {
+	return serializer.SerializeAny(
+		serializer.Int(a.Width),
+		serializer.Int(a.Height),
+		serializer.Int(a.Depth),
+		serializer.Float64(a.FlipProb),
+		serializer.Int(a.MaxShift),
+		serializer.Float64(a.MinCropScale),
+		serializer.Float64(a.Brightness),
+		serializer.Float64(a.Contrast),
+		serializer.Float64(a.Saturation),
+	)
+}
+
+// batchVec augments a constant batch of n images.
+// The result is always a copy, even if a is nil.
+func (a *Augmentation) batchVec(v linalg.Vector, n int) linalg.Vector {
+	if a == nil {
+		return append(linalg.Vector{}, v...)
+	}
+	return a.Batch(&autofunc.Variable{Vector: v}, n).Output()
+}
+
+// augmentParams stores the random choices made when
+// augmenting a single image.
+type augmentParams struct {
+	// Sources maps each output index to an input index,
+	// or to -1 if the output value should be 0.
+	Sources []int
+
+	Saturation float64
+	Contrast   float64
+	Brightness float64
+}
+
+func (a *Augmentation) randomParams() *augmentParams {
+	flip := rand.Float64() < a.FlipProb
+	var shiftX, shiftY int
+	if a.MaxShift > 0 {
+		shiftX = rand.Intn(a.MaxShift*2+1) - a.MaxShift
+		shiftY = rand.Intn(a.MaxShift*2+1) - a.MaxShift
+	}
+	cropX, cropY, cropWidth, cropHeight := 0, 0, a.Width, a.Height
+	if a.MinCropScale > 0 && a.MinCropScale < 1 {
+		scale := a.MinCropScale + rand.Float64()*(1-a.MinCropScale)
+		cropWidth = maxInt(1, int(float64(a.Width)*scale+0.5))
+		cropHeight = maxInt(1, int(float64(a.Height)*scale+0.5))
+		cropX = rand.Intn(a.Width - cropWidth + 1)
+		cropY = rand.Intn(a.Height - cropHeight + 1)
+	}
+
+	res := &augmentParams{
+		Sources:    make([]int, a.Width*a.Height*a.Depth),
+		Saturation: 1 + (rand.Float64()*2-1)*a.Saturation,
+		Contrast:   1 + (rand.Float64()*2-1)*a.Contrast,
+		Brightness: (rand.Float64()*2 - 1) * a.Brightness,
+	}
+	var idx int
+	for y := 0; y < a.Height; y++ {
+		for x := 0; x < a.Width; x++ {
+			srcX, srcY := x-shiftX, y-shiftY
+			inBounds := srcX >= 0 && srcY >= 0 && srcX < a.Width && srcY < a.Height
+			srcX = cropX + srcX*cropWidth/a.Width
+			srcY = cropY + srcY*cropHeight/a.Height
+			if flip {
+				srcX = a.Width - (srcX + 1)
+			}
+			for z := 0; z < a.Depth; z++ {
+				if inBounds {
+					res.Sources[idx] = (srcY*a.Width+srcX)*a.Depth + z
+				} else {
+					res.Sources[idx] = -1
+				}
+				idx++
+			}
+		}
+	}
+	return res
+}
+
+func (a *Augmentation) forward(p *augmentParams, image linalg.Vector) linalg.Vector {
+	res := make(linalg.Vector, len(image))
+	for i, src := range p.Sources {
+		if src >= 0 {
+			res[i] = image[src]
+		}
+	}
+	if a.Depth == 3 {
+		mixMeans(res, 3, p.Saturation)
+	}
+	mixMeans(res, len(res), p.Contrast)
+	for i := range res {
+		res[i] += p.Brightness
+	}
+	return res
+}
+
+func (a *Augmentation) backward(p *augmentParams, upstream linalg.Vector) linalg.Vector {
+	upstream = append(linalg.Vector{}, upstream...)
+	mixMeans(upstream, len(upstream), p.Contrast)
+	if a.Depth == 3 {
+		mixMeans(upstream, 3, p.Saturation)
+	}
+	res := make(linalg.Vector, len(upstream))
+	for i, src := range p.Sources {
+		if src >= 0 {
+			res[src] += upstream[i]
+		}
+	}
+	return res
+}
+
+// mixMeans replaces every value x in each consecutive
+// group of groupSize values with scale*x+(1-scale)*mean,
+// where mean is the mean of the group.
+// This is a symmetric linear map, so it is its own
+// transpose.
+func mixMeans(v linalg.Vector, groupSize int, scale float64) {
+	if scale == 1 {
+		return
+	}
+	for i := 0; i+groupSize <= len(v); i += groupSize {
+		group := v[i : i+groupSize]
+		var mean float64
+		for _, x := range group {
+			mean += x
+		}
+		mean /= float64(groupSize)
+		for j, x := range group {
+			group[j] = scale*x + (1-scale)*mean
+		}
+	}
+}
+
+type augmentResult struct {
+	Input     autofunc.Result
+	Params    []*augmentParams
+	OutputVec linalg.Vector
+	Augment   *Augmentation
+}
+
+func (a *augmentResult) Output() linalg.Vector {
+	return a.OutputVec
+}
+
+func (a *augmentResult) Constant(g autofunc.Gradient) bool {
+	return a.Input.Constant(g)
+}
+
+func (a *augmentResult) PropagateGradient(upstream linalg.Vector, grad autofunc.Gradient) {
+	if a.Input.Constant(grad) {
+		return
+	}
+	imageSize := len(upstream) / len(a.Params)
+	var downstream linalg.Vector
+	for i, p := range a.Params {
+		subUpstream := upstream[i*imageSize : (i+1)*imageSize]
+		downstream = append(downstream, a.Augment.backward(p, subUpstream)...)
+	}
+	a.Input.PropagateGradient(downstream, grad)
+}
+
+// AugmentedSet is an sgd.SampleSet which randomly
+// augments the images in another sample set every time
+// a sample is requested.
+//
+// The underlying samples must be neuralnet.VectorSamples.
+// Their outputs are left unchanged.
+type AugmentedSet struct {
+	Samples sgd.SampleSet
+	Augment *Augmentation
+}
+
+// Len returns the number of samples.
+func (a *AugmentedSet) Len() int {
+	return a.Samples.Len()
+}
+
+// Swap swaps two samples.
+func (a *AugmentedSet) Swap(i, j int) {
+	a.Samples.Swap(i, j)
+}
+
+// GetSample returns a randomly augmented sample.
+func (a *AugmentedSet) GetSample(idx int) interface{} {
+	sample := a.Samples.GetSample(idx).(neuralnet.VectorSample)
+	sample.Input = a.Augment.batchVec(sample.Input, 1)
+	return sample
+}
+
+// Copy creates a copy of the set.
+func (a *AugmentedSet) Copy() sgd.SampleSet {
+	return &AugmentedSet{Samples: a.Samples.Copy(), Augment: a.Augment}
+}
+
+// Subset returns a subset of the set.
+func (a *AugmentedSet) Subset(start, end int) sgd.SampleSet {
+	return &AugmentedSet{Samples: a.Samples.Subset(start, end), Augment: a.Augment}
+}
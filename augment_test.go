@@ -0,0 +1,73 @@
+package gans
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/unixpickle/num-analysis/linalg"
+	"github.com/unixpickle/weakai/neuralnet"
+)
+
+func TestAugmentationTranspose(t *testing.T) {
+	a := &Augmentation{
+		Width:        5,
+		Height:       4,
+		Depth:        3,
+		FlipProb:     0.5,
+		MaxShift:     2,
+		MinCropScale: 0.5,
+		Brightness:   0.3,
+		Contrast:     0.4,
+		Saturation:   0.5,
+	}
+	size := a.Width * a.Height * a.Depth
+	for i := 0; i < 20; i++ {
+		p := a.randomParams()
+		x := randomVec(size)
+		y := randomVec(size)
+
+		// The augmentation is affine, so subtracting its
+		// constant term leaves a linear map whose transpose
+		// is backward.
+		offset := a.forward(p, make(linalg.Vector, size))
+		forward := a.forward(p, x)
+		for j, o := range offset {
+			forward[j] -= o
+		}
+		expected := forward.Dot(y)
+		actual := x.Dot(a.backward(p, y))
+		if math.Abs(expected-actual) > 1e-8 {
+			t.Errorf("trial %d: expected %f but got %f", i, expected, actual)
+		}
+	}
+}
+
+func TestAugmentationNilCopies(t *testing.T) {
+	var a *Augmentation
+	tensor := &neuralnet.Tensor3{Width: 1, Height: 1, Depth: 2, Data: []float64{1, 2}}
+	res := a.Tensor(tensor)
+	res.Data[0] = 3
+	if tensor.Data[0] != 1 {
+		t.Error("augmented tensor shares data with its input")
+	}
+}
+
+func TestAugmentationSizeMismatch(t *testing.T) {
+	a := &Augmentation{Width: 2, Height: 2, Depth: 1}
+	tensor := &neuralnet.Tensor3{Width: 2, Height: 1, Depth: 2, Data: make([]float64, 4)}
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic for mismatched tensor dimensions")
+		}
+	}()
+	a.Tensor(tensor)
+}
+
+func randomVec(size int) linalg.Vector {
+	res := make(linalg.Vector, size)
+	for i := range res {
+		res[i] = rand.NormFloat64()
+	}
+	return res
+}
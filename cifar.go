@@ -0,0 +1,100 @@
+package gans
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"os"
+
+	"github.com/unixpickle/num-analysis/linalg"
+	"github.com/unixpickle/sgd"
+	"github.com/unixpickle/weakai/neuralnet"
+)
+
+// CIFARSize and CIFARDepth are the dimensions of the
+// tensors produced by the CIFAR loaders.
+const (
+	CIFARSize  = 32
+	CIFARDepth = 3
+
+	cifarImageBytes = CIFARSize * CIFARSize * CIFARDepth
+)
+
+// LoadCIFAR10 reads CIFAR-10 binary batch files.
+//
+// Each sample is a neuralnet.VectorSample whose input is
+// the data of a 32x32x3 neuralnet.Tensor3 with values in
+// [0, 1], and whose output is a one-hot label vector.
+func LoadCIFAR10(paths ...string) (sgd.SliceSampleSet, error) {
+	return loadCIFAR(paths, 1, 0, 10)
+}
+
+// LoadCIFAR100 reads CIFAR-100 binary files.
+// If fine is true, the 100 fine labels are used;
+// otherwise, the 20 coarse labels are used.
+//
+// Samples are in the same format as for LoadCIFAR10.
+func LoadCIFAR100(fine bool, paths ...string) (sgd.SliceSampleSet, error) {
+	if fine {
+		return loadCIFAR(paths, 2, 1, 100)
+	}
+	return loadCIFAR(paths, 2, 0, 20)
+}
+
+func loadCIFAR(paths []string, labelBytes, labelIdx,
+	numLabels int) (sgd.SliceSampleSet, error) {
+	var res sgd.SliceSampleSet
+	for _, path := range paths {
+		samples, err := readCIFARFile(path, labelBytes, labelIdx, numLabels)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, samples...)
+	}
+	return res, nil
+}
+
+func readCIFARFile(path string, labelBytes, labelIdx,
+	numLabels int) ([]interface{}, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	record := make([]byte, labelBytes+cifarImageBytes)
+	var res []interface{}
+	for {
+		if _, err := io.ReadFull(r, record); err == io.EOF {
+			return res, nil
+		} else if err == io.ErrUnexpectedEOF {
+			return nil, errors.New("truncated CIFAR record in " + path)
+		} else if err != nil {
+			return nil, err
+		}
+		label := int(record[labelIdx])
+		if label >= numLabels {
+			return nil, errors.New("invalid CIFAR label in " + path)
+		}
+		res = append(res, cifarSample(record[labelBytes:], label, numLabels))
+	}
+}
+
+// cifarSample converts a CIFAR image, which is stored as
+// three planes of row-major bytes, into a sample.
+func cifarSample(pixels []byte, label, numLabels int) neuralnet.VectorSample {
+	tensor := neuralnet.NewTensor3(CIFARSize, CIFARSize, CIFARDepth)
+	planeSize := CIFARSize * CIFARSize
+	for z := 0; z < CIFARDepth; z++ {
+		for y := 0; y < CIFARSize; y++ {
+			for x := 0; x < CIFARSize; x++ {
+				val := pixels[z*planeSize+y*CIFARSize+x]
+				tensor.Set(x, y, z, float64(val)/0xff)
+			}
+		}
+	}
+	output := make(linalg.Vector, numLabels)
+	output[label] = 1
+	return neuralnet.VectorSample{Input: tensor.Data, Output: output}
+}
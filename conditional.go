@@ -0,0 +1,135 @@
+package gans
+
+import (
+	"math/rand"
+
+	"github.com/unixpickle/autofunc/seqfunc"
+	"github.com/unixpickle/num-analysis/linalg"
+	"github.com/unixpickle/sgd"
+	"github.com/unixpickle/weakai/rnn"
+	"github.com/unixpickle/weakai/rnn/seqtoseq"
+)
+
+// A rollout stores sequences sampled from a Recurrent
+// generator along with the inputs which produced them.
+type rollout struct {
+	// Inputs contains the generator's input sequences.
+	Inputs [][]linalg.Vector
+
+	// Seqs contains the one-hot sampled sequences,
+	// including any real prefixes.
+	Seqs [][]linalg.Vector
+
+	// PrefixLens contains the length of the real prefix of
+	// each sequence, or is nil if there are no prefixes.
+	PrefixLens []int
+
+	// PolicyOut is the generator's output for Inputs.
+	// It is nil until policy is called, unless the outputs
+	// were computed while sampling.
+	PolicyOut seqfunc.Result
+}
+
+// policy returns the generator's log-probability outputs
+// for the rollout, computing them if necessary.
+// Since the inputs include every previously sampled
+// symbol, running the generator on them reproduces the
+// distributions which the symbols were sampled from.
+func (r *rollout) policy(gen seqfunc.RFunc) seqfunc.Result {
+	if r.PolicyOut == nil {
+		r.PolicyOut = gen.ApplySeqs(seqfunc.ConstResult(r.Inputs))
+	}
+	return r.PolicyOut
+}
+
+func (r *rollout) prefixLen(i int) int {
+	if r.PrefixLens == nil {
+		return 0
+	}
+	return r.PrefixLens[i]
+}
+
+// Continue feeds a prefix to a conditional generator and
+// samples the given number of symbols after it.
+//
+// This requires Conditional to be set and Vocab to be
+// non-nil, since the number of symbols must be known.
+func (r *Recurrent) Continue(prefix []int, length int) []int {
+	if !r.Conditional {
+		panic("Continue requires a conditional generator")
+	} else if r.Vocab == nil {
+		panic("Continue requires a vocabulary")
+	}
+	runner := r.generatorRunner()
+	prev := make(linalg.Vector, r.Vocab.Size())
+	for _, symbol := range prefix {
+		runner.StepTime(r.conditionalInput(prev))
+		prev = OneHotSeq([]int{symbol}, len(prev))[0]
+	}
+	res := make([]int, length)
+	for i := range res {
+		out := runner.StepTime(r.conditionalInput(prev))
+		res[i] = sampleVector(out)
+		prev = OneHotSeq([]int{res[i]}, len(prev))[0]
+	}
+	return res
+}
+
+// conditionalRollout runs the generator on a random
+// prefix of every sequence in s and samples the rest of
+// each sequence.
+func (r *Recurrent) conditionalRollout(s sgd.SampleSet) *rollout {
+	res := &rollout{}
+	for i := 0; i < s.Len(); i++ {
+		realSeq := s.GetSample(i).(seqtoseq.Sample).Inputs
+		var prefixLen int
+		if maxLen := minInt(r.PrefixLen, len(realSeq)-1); maxLen > 0 {
+			prefixLen = rand.Intn(maxLen + 1)
+		}
+
+		runner := r.generatorRunner()
+		var inputs, seq []linalg.Vector
+		var prev linalg.Vector
+		for t, realVec := range realSeq {
+			if t == 0 {
+				prev = make(linalg.Vector, len(realVec))
+			}
+			input := r.conditionalInput(prev)
+			out := runner.StepTime(input)
+			if t < prefixLen {
+				prev = realVec
+			} else {
+				prev = OneHotSeq([]int{sampleVector(out)}, len(out))[0]
+			}
+			inputs = append(inputs, input)
+			seq = append(seq, prev)
+		}
+		res.Inputs = append(res.Inputs, inputs)
+		res.Seqs = append(res.Seqs, seq)
+		res.PrefixLens = append(res.PrefixLens, prefixLen)
+	}
+	return res
+}
+
+func (r *Recurrent) conditionalInput(prev linalg.Vector) linalg.Vector {
+	res := make(linalg.Vector, r.RandomSize, r.RandomSize+len(prev))
+	for i := range res {
+		res[i] = rand.NormFloat64()
+	}
+	return append(res, prev...)
+}
+
+func (r *Recurrent) generatorRunner() *rnn.Runner {
+	block, ok := r.Generator.(*rnn.BlockSeqFunc)
+	if !ok {
+		panic("conditional generator must be an *rnn.BlockSeqFunc")
+	}
+	return &rnn.Runner{Block: block.B}
+}
+
+func minInt(x, y int) int {
+	if x < y {
+		return x
+	}
+	return y
+}
@@ -0,0 +1,193 @@
+package gans
+
+import (
+	"errors"
+	"math/rand"
+
+	"github.com/unixpickle/autofunc"
+	"github.com/unixpickle/autofunc/seqfunc"
+	"github.com/unixpickle/num-analysis/linalg"
+	"github.com/unixpickle/serializer"
+	"github.com/unixpickle/sgd"
+	"github.com/unixpickle/weakai/neuralnet"
+	"github.com/unixpickle/weakai/rnn/seqtoseq"
+)
+
+func init() {
+	var c ContinuousRecurrent
+	serializer.RegisterTypedDeserializer(c.SerializerType(), DeserializeContinuousRecurrent)
+}
+
+// ContinuousRecurrent trains a GAN comprised of two RNNs
+// on sequences of real-valued vectors, such as time
+// series.
+//
+// Unlike Recurrent, the generator's outputs are fed
+// directly into the discriminator, and the generator is
+// trained by back-propagating through the discriminator.
+type ContinuousRecurrent struct {
+	GenIterations  int
+	DiscIterations int
+
+	GenTrans  sgd.Transformer
+	DiscTrans sgd.Transformer
+
+	// Discriminator outputs values which are meant to be fed
+	// into a sigmoid, with higher values indicating "real"
+	// samples.
+	Discriminator seqfunc.RFunc
+
+	// Generator takes sequences of random vectors and makes
+	// synthetic sequences.
+	Generator seqfunc.RFunc
+
+	// RandomSize specifies the vector input size of the
+	// generator.
+	RandomSize int
+
+	// Noise, if non-nil, configures label smoothing,
+	// label flipping, and instance noise for the
+	// discriminator.
+	Noise *DiscNoise
+
+	// Mean and Stddev, if non-nil, are the statistics which
+	// were used to normalize the training data (e.g. those
+	// of a SeriesSet), so that generated sequences can be
+	// mapped back to real units with Denormalize.
+	Mean   linalg.Vector
+	Stddev linalg.Vector
+
+	iterIdx int
+}
+
+// DeserializeContinuousRecurrent deserializes a
+// ContinuousRecurrent instance.
+func DeserializeContinuousRecurrent(d []byte) (*ContinuousRecurrent, error) {
+	slice, err := serializer.DeserializeSlice(d)
+	if err != nil {
+		return nil, err
+	}
+	if len(slice) != 4 && len(slice) != 6 {
+		return nil, errors.New("invalid ContinuousRecurrent slice")
+	}
+	disc, ok1 := slice[0].(seqfunc.RFunc)
+	gen, ok2 := slice[1].(seqfunc.RFunc)
+	randomSize, ok3 := slice[2].(serializer.Int)
+	noise, ok4 := slice[3].(*DiscNoise)
+	if !ok1 || !ok2 || !ok3 || !ok4 {
+		return nil, errors.New("invalid ContinuousRecurrent slice")
+	}
+	res := &ContinuousRecurrent{
+		Discriminator: disc,
+		Generator:     gen,
+		RandomSize:    int(randomSize),
+		Noise:         noise,
+	}
+	if len(slice) == 6 {
+		mean, ok1 := slice[4].(serializer.Float64Slice)
+		stddev, ok2 := slice[5].(serializer.Float64Slice)
+		if !ok1 || !ok2 || len(mean) != len(stddev) {
+			return nil, errors.New("invalid ContinuousRecurrent slice")
+		}
+		if len(mean) > 0 {
+			res.Mean = linalg.Vector(mean)
+			res.Stddev = linalg.Vector(stddev)
+		}
+	}
+	return res, nil
+}
+
+// SerializerType returns the unique ID used to serialize
+// ContinuousRecurrent instances with the serializer
+// package.
+func (c *ContinuousRecurrent) SerializerType() string {
+	return "github.com/unixpickle/gans.ContinuousRecurrent"
+}
+
+// Serialize serializes the instance.
+func (c *ContinuousRecurrent) Serialize() ([]byte, error) {
+	noise := c.Noise
+	if noise == nil {
+		noise = &DiscNoise{}
+	}
+	return serializer.SerializeAny(c.Discriminator, c.Generator,
+		serializer.Int(c.RandomSize), noise, serializer.Float64Slice(c.Mean),
+		serializer.Float64Slice(c.Stddev))
+}
+
+// Gradient computes the gradient to be descended for the
+// next training step.
+func (c *ContinuousRecurrent) Gradient(s sgd.SampleSet) autofunc.Gradient {
+	genGrad := autofunc.NewGradient(c.Generator.(sgd.Learner).Parameters())
+	discGrad := autofunc.NewGradient(c.Discriminator.(sgd.Learner).Parameters())
+
+	subIdx := c.iterIdx % (c.GenIterations + c.DiscIterations)
+	c.iterIdx++
+
+	if subIdx < c.DiscIterations {
+		c.DiscCost(s).PropagateGradient([]float64{1}, discGrad)
+		c.Noise.step()
+		if c.DiscTrans != nil {
+			discGrad = c.DiscTrans.Transform(discGrad)
+		}
+	} else {
+		c.GenCost(s).PropagateGradient([]float64{1}, genGrad)
+		if c.GenTrans != nil {
+			genGrad = c.GenTrans.Transform(genGrad)
+		}
+	}
+
+	res := autofunc.Gradient{}
+	for _, g := range []autofunc.Gradient{genGrad, discGrad} {
+		for k, v := range g {
+			res[k] = v
+		}
+	}
+	return res
+}
+
+// DiscCost samples the discriminator cost.
+func (c *ContinuousRecurrent) DiscCost(s sgd.SampleSet) autofunc.Result {
+	genSeqs := c.Generator.ApplySeqs(generatorSeed(s, c.RandomSize)).OutputSeqs()
+	var realSeqs [][]linalg.Vector
+	for i := 0; i < s.Len(); i++ {
+		realSeqs = append(realSeqs, s.GetSample(i).(seqtoseq.Sample).Inputs)
+	}
+	return seqDiscCost(c.Discriminator.ApplySeqs, c.Noise, realSeqs, genSeqs)
+}
+
+// GenCost samples the generator cost, which is the
+// cross-entropy cost of the discriminator when generated
+// sequences are labeled as real.
+// As in DiscCost, the discriminator sees the generated
+// sequences with instance noise from Noise.
+// The cost can be back-propagated through the generator.
+func (c *ContinuousRecurrent) GenCost(s sgd.SampleSet) autofunc.Result {
+	genOut := c.Generator.ApplySeqs(generatorSeed(s, c.RandomSize))
+	classifications := c.Discriminator.ApplySeqs(seqfunc.Map(genOut, c.Noise.noisyResult))
+	costFunc := func(a autofunc.Result) autofunc.Result {
+		return neuralnet.SigmoidCECost{}.Cost([]float64{1}, a)
+	}
+	return seqfunc.AddAll(seqfunc.Map(classifications, costFunc))
+}
+
+// Generate samples a sequence of the given length from
+// the generator.
+func (c *ContinuousRecurrent) Generate(length int) []linalg.Vector {
+	seed := make([]linalg.Vector, length)
+	for i := range seed {
+		seed[i] = make(linalg.Vector, c.RandomSize)
+		for j := range seed[i] {
+			seed[i][j] = rand.NormFloat64()
+		}
+	}
+	out := c.Generator.ApplySeqs(seqfunc.ConstResult([][]linalg.Vector{seed}))
+	return out.OutputSeqs()[0]
+}
+
+// Denormalize maps a sequence, such as one produced by
+// Generate, back to real units using Mean and Stddev.
+// If Mean is nil, seq is returned as-is.
+func (c *ContinuousRecurrent) Denormalize(seq []linalg.Vector) []linalg.Vector {
+	return DenormalizeSeries(seq, c.Mean, c.Stddev)
+}
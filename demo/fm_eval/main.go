@@ -0,0 +1,118 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"time"
+
+	"github.com/unixpickle/gans"
+	"github.com/unixpickle/gans/eval"
+	"github.com/unixpickle/mnist"
+	"github.com/unixpickle/num-analysis/linalg"
+	"github.com/unixpickle/weakai/neuralnet"
+)
+
+func main() {
+	rand.Seed(time.Now().UnixNano())
+
+	var sampleCount int
+	var splits int
+	var featureLayers int
+	var parzenCenters int
+	var parzenValidation int
+	flag.IntVar(&sampleCount, "samples", 1000, "number of samples to evaluate")
+	flag.IntVar(&splits, "splits", 10, "number of splits for the Inception Score")
+	flag.IntVar(&featureLayers, "features", -1,
+		"classifier layers used for features (default: all but the last)")
+	flag.IntVar(&parzenCenters, "parzen", 0,
+		"number of generated samples for a Parzen-window estimate (0 to disable)")
+	flag.IntVar(&parzenValidation, "parzen-validation", 1000,
+		"number of training samples used to choose the Parzen bandwidth")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: fm_eval [flags] <model> <classifier>")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() != 2 {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	model := readModel(flag.Arg(0))
+	classifier := readClassifier(flag.Arg(1))
+	if featureLayers < 0 {
+		featureLayers = len(classifier) - 1
+	}
+	evaluator := &eval.Classifier{Network: classifier, FeatureLayers: featureLayers}
+
+	generated := make([]linalg.Vector, sampleCount)
+	for i := range generated {
+		generated[i] = model.Generate()
+	}
+	testSet := mnist.LoadTestingDataSet().SGDSampleSet()
+	var real []linalg.Vector
+	for i := 0; i < testSet.Len() && i < sampleCount; i++ {
+		real = append(real, testSet.GetSample(i).(neuralnet.VectorSample).Input)
+	}
+
+	isMean, isStd := evaluator.InceptionScore(generated, splits)
+	realMean, realStd := evaluator.InceptionScore(real, splits)
+	fmt.Printf("inception score: %f +/- %f (real data: %f +/- %f)\n", isMean, isStd,
+		realMean, realStd)
+	fid, err := evaluator.FrechetDistance(real, generated)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Failed to compute frechet distance:", err)
+		os.Exit(1)
+	}
+	fmt.Printf("frechet distance: %f\n", fid)
+
+	if parzenCenters > 0 {
+		trainSet := mnist.LoadTrainingDataSet().SGDSampleSet()
+		if parzenValidation < 1 || parzenValidation > trainSet.Len() {
+			fmt.Fprintf(os.Stderr, "Invalid -parzen-validation: must be in [1, %d]\n",
+				trainSet.Len())
+			os.Exit(1)
+		}
+		validation := trainSet.Subset(trainSet.Len()-parzenValidation, trainSet.Len())
+		parzen := eval.NewParzen(model.Generate, parzenCenters)
+		sigma, err := parzen.FitSigma(validation, eval.ParzenSigmas(0.05, 1, 20))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Failed to fit Parzen window:", err)
+			os.Exit(1)
+		}
+		fmt.Printf("parzen log-likelihood: %f (sigma=%f)\n",
+			parzen.MeanLogLikelihood(testSet), sigma)
+	}
+}
+
+func readModel(path string) *gans.FM {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Failed to read model:", err)
+		os.Exit(1)
+	}
+	model, err := gans.DeserializeFM(data)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Failed to deserialize model:", err)
+		os.Exit(1)
+	}
+	return model
+}
+
+func readClassifier(path string) neuralnet.Network {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Failed to read classifier:", err)
+		os.Exit(1)
+	}
+	net, err := neuralnet.DeserializeNetwork(data)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Failed to deserialize classifier:", err)
+		os.Exit(1)
+	}
+	return net
+}
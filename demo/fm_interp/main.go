@@ -0,0 +1,90 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"image/gif"
+	"image/png"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/unixpickle/gans"
+	"github.com/unixpickle/num-analysis/linalg"
+	"github.com/unixpickle/weakai/neuralnet"
+)
+
+func main() {
+	rand.Seed(time.Now().UnixNano())
+
+	var points, steps, delay int
+	var spherical bool
+	flag.IntVar(&points, "points", 2, "number of random latent vectors to visit")
+	flag.IntVar(&steps, "steps", 8, "interpolation steps between latent vectors")
+	flag.IntVar(&delay, "delay", 10, "GIF frame delay in 100ths of a second")
+	flag.BoolVar(&spherical, "slerp", false, "use spherical interpolation")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: fm_interp [flags] <model> <output.png|output.gif>")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() != 2 || points < 2 {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	model := readModel(flag.Arg(0))
+	latents := make([]linalg.Vector, points)
+	for i := range latents {
+		latents[i] = make(linalg.Vector, model.RandomSize)
+		for j := range latents[i] {
+			latents[i][j] = rand.NormFloat64()
+		}
+	}
+	if spherical {
+		latents = gans.SlerpLatents(latents, steps)
+	} else {
+		latents = gans.LerpLatents(latents, steps)
+	}
+
+	render := func(latent linalg.Vector) *neuralnet.Tensor3 {
+		out := model.GenerateLatent(latent)
+		for i, x := range out {
+			out[i] = 1 - x
+		}
+		return &neuralnet.Tensor3{Width: 28, Height: 28, Depth: 1, Data: out}
+	}
+
+	outFile, err := os.Create(flag.Arg(1))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	defer outFile.Close()
+	if filepath.Ext(flag.Arg(1)) == ".gif" {
+		err = gif.EncodeAll(outFile, gans.InterpolationGIF(latents, delay, render))
+	} else {
+		err = png.Encode(outFile, gans.InterpolationStrip(latents, render))
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func readModel(path string) *gans.FM {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Failed to read model:", err)
+		os.Exit(1)
+	}
+	model, err := gans.DeserializeFM(data)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Failed to deserialize model:", err)
+		os.Exit(1)
+	}
+	return model
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"image/png"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/unixpickle/autofunc"
+	"github.com/unixpickle/gans"
+	"github.com/unixpickle/gans/eval"
+	"github.com/unixpickle/mnist"
+	"github.com/unixpickle/num-analysis/linalg"
+	"github.com/unixpickle/weakai/neuralnet"
+)
+
+func main() {
+	rand.Seed(time.Now().UnixNano())
+
+	var sampleCount, neighborCount int
+	var useFeatures bool
+	flag.IntVar(&sampleCount, "samples", 10, "number of generated samples")
+	flag.IntVar(&neighborCount, "k", 5, "number of neighbors per sample")
+	flag.BoolVar(&useFeatures, "features", false,
+		"measure distances in the discriminator's feature space")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: fm_neighbors [flags] <model> <output.png>")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() != 2 || sampleCount < 1 || neighborCount < 1 {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	model := readModel(flag.Arg(0))
+	samples := make([]linalg.Vector, sampleCount)
+	for i := range samples {
+		samples[i] = model.Generate()
+	}
+
+	var features func(linalg.Vector) linalg.Vector
+	if useFeatures {
+		featureNet := model.Discriminator[:model.FeatureLayers]
+		features = func(v linalg.Vector) linalg.Vector {
+			return featureNet.Apply(&autofunc.Variable{Vector: v}).Output()
+		}
+	}
+
+	train := mnist.LoadTrainingDataSet().SGDSampleSet()
+	neighbors := eval.NearestNeighbors(samples, train, neighborCount, features)
+
+	var closest []float64
+	for _, n := range neighbors {
+		closest = append(closest, n[0].Distance)
+	}
+	sort.Float64s(closest)
+	fmt.Printf("closest distances: min=%f median=%f max=%f\n", closest[0],
+		closest[len(closest)/2], closest[len(closest)-1])
+
+	img := eval.NeighborImage(samples, train, neighbors,
+		func(v linalg.Vector) *neuralnet.Tensor3 {
+			out := make(linalg.Vector, len(v))
+			for i, x := range v {
+				out[i] = 1 - x
+			}
+			return &neuralnet.Tensor3{Width: 28, Height: 28, Depth: 1, Data: out}
+		})
+	outFile, err := os.Create(flag.Arg(1))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	defer outFile.Close()
+	png.Encode(outFile, img)
+}
+
+func readModel(path string) *gans.FM {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Failed to read model:", err)
+		os.Exit(1)
+	}
+	model, err := gans.DeserializeFM(data)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Failed to deserialize model:", err)
+		os.Exit(1)
+	}
+	return model
+}
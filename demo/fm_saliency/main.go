@@ -0,0 +1,47 @@
+package main
+
+import (
+	"fmt"
+	"image/png"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"time"
+
+	"github.com/unixpickle/gans"
+	"github.com/unixpickle/weakai/neuralnet"
+)
+
+func main() {
+	rand.Seed(time.Now().UnixNano())
+
+	if len(os.Args) != 3 {
+		fmt.Fprintln(os.Stderr, "Usage: fm_saliency <model> <output.png>")
+		os.Exit(1)
+	}
+
+	data, err := ioutil.ReadFile(os.Args[1])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Failed to read model:", err)
+		os.Exit(1)
+	}
+	model, err := gans.DeserializeFM(data)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Failed to deserialize model:", err)
+		os.Exit(1)
+	}
+
+	renderings := gans.GridSample(5, 8, func() *neuralnet.Tensor3 {
+		sample := model.Generate()
+		saliency := model.Saliency(sample)
+		img := &neuralnet.Tensor3{Width: 28, Height: 28, Depth: 1, Data: sample}
+		return gans.SaliencyTensor(img, saliency)
+	})
+	outFile, err := os.Create(os.Args[2])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	defer outFile.Close()
+	png.Encode(outFile, renderings)
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"image/png"
+	"log"
+	"math/rand"
+	"os"
+	"time"
+
+	"github.com/unixpickle/gans"
+	"github.com/unixpickle/gans/eval"
+	"github.com/unixpickle/num-analysis/linalg"
+	"github.com/unixpickle/sgd"
+	"github.com/unixpickle/weakai/neuralnet"
+)
+
+const (
+	StepSize   = 0.001
+	BatchSize  = 64
+	Iterations = 5000
+	LogEvery   = 250
+
+	ModeCount   = 8
+	ModeRadius  = 2
+	ModeStddev  = 0.02
+	SampleCount = 10000
+
+	EvalCount    = 2500
+	MinModeCount = 20
+
+	ImageSize  = 400
+	ImageScale = 3
+)
+
+func main() {
+	rand.Seed(time.Now().UnixNano())
+
+	if len(os.Args) != 2 {
+		fmt.Fprintln(os.Stderr, "Usage: mixture_gen <output.png>")
+		os.Exit(1)
+	}
+
+	mixture := eval.RingMixture(ModeCount, ModeRadius, ModeStddev)
+	samples := mixture.SampleSet(SampleCount)
+	fm := createModel()
+
+	var iteration int
+	sgd.SGDMini(fm, samples, StepSize, BatchSize, func(s sgd.SampleSet) bool {
+		if iteration%LogEvery == 0 {
+			generated := make([]linalg.Vector, EvalCount)
+			for i := range generated {
+				generated[i] = fm.Generate()
+			}
+			coverage := mixture.Coverage(generated, MinModeCount)
+			log.Printf("iteration %d: modes=%d/%d high_quality=%f", iteration,
+				coverage.ModesCovered, ModeCount, coverage.HighQuality)
+		}
+		iteration++
+		return iteration < Iterations
+	})
+
+	real := make([]linalg.Vector, EvalCount)
+	generated := make([]linalg.Vector, EvalCount)
+	for i := range real {
+		real[i], _ = mixture.Sample()
+		generated[i] = fm.Generate()
+	}
+	img := gans.ScatterPlot(ImageSize, ImageScale, [][]linalg.Vector{real, generated},
+		[]color.Color{color.Gray{Y: 0xa0}, gans.ScatterPointColor})
+	outFile, err := os.Create(os.Args[1])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	defer outFile.Close()
+	png.Encode(outFile, img)
+}
+
+func createModel() *gans.FM {
+	discrim := neuralnet.Network{
+		neuralnet.NewDenseLayer(2, 128),
+		neuralnet.ReLU{},
+		neuralnet.NewDenseLayer(128, 128),
+		neuralnet.ReLU{},
+		neuralnet.NewDenseLayer(128, 1),
+	}
+	gen := neuralnet.Network{
+		neuralnet.NewDenseLayer(16, 128),
+		neuralnet.ReLU{},
+		neuralnet.NewDenseLayer(128, 128),
+		neuralnet.ReLU{},
+		neuralnet.NewDenseLayer(128, 2),
+	}
+	return &gans.FM{
+		Discriminator: discrim,
+		FeatureLayers: len(discrim) - 1,
+		Generator:     gen,
+		RandomSize:    16,
+	}
+}
@@ -7,11 +7,12 @@ import (
 	"log"
 	"math/rand"
 	"os"
+	"path/filepath"
 	"time"
 
-	"github.com/unixpickle/autofunc"
 	"github.com/unixpickle/gans"
 	"github.com/unixpickle/mnist"
+	"github.com/unixpickle/num-analysis/linalg"
 	"github.com/unixpickle/sgd"
 	"github.com/unixpickle/weakai/neuralnet"
 )
@@ -19,29 +20,55 @@ import (
 const (
 	StepSize  = 0.001
 	BatchSize = 96
+
+	EMADecay  = 0.999
+	EMAWarmup = 100
+
+	TimelapseInterval = 200
+	TimelapseDelay    = 20
 )
 
 func main() {
 	rand.Seed(time.Now().UnixNano())
 
-	if len(os.Args) != 3 {
-		fmt.Fprintln(os.Stderr, "Usage: mnist_gen <model_out> <output.png>")
+	if len(os.Args) != 3 && len(os.Args) != 4 {
+		fmt.Fprintln(os.Stderr, "Usage: mnist_gen <model_out> <output.png> [timelapse_dir]")
 		os.Exit(1)
 	}
 
 	fm := createModel()
 	dataSet := mnist.LoadTrainingDataSet()
 	samples := dataSet.SGDSampleSet()
+
+	var timelapse *gans.Timelapse
+	if len(os.Args) == 4 {
+		timelapse = &gans.Timelapse{
+			Dir:      os.Args[3],
+			Interval: TimelapseInterval,
+			Rows:     5,
+			Cols:     8,
+			Render:   renderDigit,
+		}
+	}
+
 	var iteration int
 	sgd.SGDMini(fm, samples, StepSize, BatchSize, func(s sgd.SampleSet) bool {
+		fm.UpdateEMA()
 		posCost := fm.SampleRealCost(samples)
 		genCost := fm.SampleGenCost()
 		log.Printf("iteration %d: real_cost=%f  gen_cost=%f", iteration,
 			posCost, genCost)
+		if timelapse != nil {
+			if err := timelapse.Step(fm, iteration); err != nil {
+				log.Println("Timelapse failed:", err)
+			}
+		}
 		iteration++
 		return true
 	})
 
+	fm.UpdateEMA()
+
 	log.Println("Saving model...")
 	data, err := fm.Serialize()
 	if err != nil {
@@ -56,15 +83,7 @@ func main() {
 	log.Println("Creating generation grid...")
 
 	renderings := gans.GridSample(5, 8, func() *neuralnet.Tensor3 {
-		randVec := make([]float64, fm.RandomSize)
-		for i := range randVec {
-			randVec[i] = rand.NormFloat64()
-		}
-		out := fm.Generator.Apply(&autofunc.Variable{Vector: randVec}).Output()
-		for i, x := range out {
-			out[i] = 1 - x
-		}
-		return &neuralnet.Tensor3{Width: 28, Height: 28, Depth: 1, Data: out}
+		return renderDigit(fm.Generate())
 	})
 	outFile, err := os.Create(os.Args[2])
 	if err != nil {
@@ -73,6 +92,22 @@ func main() {
 	}
 	defer outFile.Close()
 	png.Encode(outFile, renderings)
+
+	if timelapse != nil {
+		log.Println("Creating timelapse...")
+		gifPath := filepath.Join(timelapse.Dir, "timelapse.gif")
+		if err := timelapse.WriteGIF(gifPath, TimelapseDelay); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+}
+
+func renderDigit(out linalg.Vector) *neuralnet.Tensor3 {
+	for i, x := range out {
+		out[i] = 1 - x
+	}
+	return &neuralnet.Tensor3{Width: 28, Height: 28, Depth: 1, Data: out}
 }
 
 func createModel() *gans.FM {
@@ -95,6 +130,8 @@ func createModel() *gans.FM {
 		FeatureLayers: len(discrim) - 2,
 		Generator:     createGenerator(),
 		RandomSize:    14 * 14,
+		EMADecay:      EMADecay,
+		EMAWarmup:     EMAWarmup,
 	}
 }
 
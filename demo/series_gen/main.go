@@ -0,0 +1,138 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"math/rand"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/unixpickle/gans"
+	"github.com/unixpickle/num-analysis/linalg"
+	"github.com/unixpickle/sgd"
+	"github.com/unixpickle/weakai/neuralnet"
+	"github.com/unixpickle/weakai/rnn"
+)
+
+const (
+	RandCount  = 20
+	HiddenSize = 100
+
+	StepSize   = 1e-3
+	BatchSize  = 32
+	Iterations = 5000
+	LogEvery   = 50
+
+	GenAtEnd = 3
+)
+
+func main() {
+	rand.Seed(time.Now().UnixNano())
+
+	var header bool
+	var window, stride int
+	flag.BoolVar(&header, "header", false, "skip the first line of the CSV file")
+	flag.IntVar(&window, "window", 50, "number of rows per sample")
+	flag.IntVar(&stride, "stride", 5, "rows between the starts of samples")
+	flag.Parse()
+
+	if len(flag.Args()) != 2 {
+		fmt.Fprintln(os.Stderr, "Usage: series_gen [flags] <data.csv> <model_file>")
+		flag.PrintDefaults()
+		os.Exit(1)
+	}
+
+	samples, err := gans.ReadCSVSeries(flag.Arg(0), &gans.CSVOptions{
+		Header:    header,
+		Window:    window,
+		Stride:    stride,
+		Normalize: true,
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Failed to read series:", err)
+		os.Exit(1)
+	}
+	model := readOrCreateModel(flag.Arg(1), len(samples.Rows[0]))
+	model.Mean = samples.Mean
+	model.Stddev = samples.Stddev
+	model.GenIterations = 1
+	model.DiscIterations = 1
+	model.GenTrans = &sgd.RMSProp{Resiliency: 0.9}
+	model.DiscTrans = &sgd.RMSProp{Resiliency: 0.9}
+
+	log.Println("Training model...")
+	var iteration int
+	sgd.SGDMini(model, samples, StepSize, BatchSize, func(s sgd.SampleSet) bool {
+		if iteration%LogEvery == 0 {
+			log.Printf("iteration %d: disc=%f gen=%f", iteration,
+				model.DiscCost(s).Output()[0], model.GenCost(s).Output()[0])
+		}
+		iteration++
+		return iteration < Iterations
+	})
+
+	data, err := model.Serialize()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Serialize failed:", err)
+		os.Exit(1)
+	}
+	if err := ioutil.WriteFile(flag.Arg(1), data, 0755); err != nil {
+		fmt.Fprintln(os.Stderr, "Save failed:", err)
+		os.Exit(1)
+	}
+
+	for i := 0; i < GenAtEnd; i++ {
+		if i > 0 {
+			fmt.Println()
+		}
+		for _, row := range model.Denormalize(model.Generate(window)) {
+			fmt.Println(formatRow(row))
+		}
+	}
+}
+
+func readOrCreateModel(path string, columns int) *gans.ContinuousRecurrent {
+	contents, err := ioutil.ReadFile(path)
+	if err == nil {
+		model, err := gans.DeserializeContinuousRecurrent(contents)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Deserialize failed:", err)
+			os.Exit(1)
+		}
+		log.Println("Loaded model.")
+		return model
+	}
+
+	log.Println("Creating new model...")
+	return &gans.ContinuousRecurrent{
+		Discriminator: &rnn.BlockSeqFunc{
+			B: rnn.StackedBlock{
+				rnn.NewLSTM(columns, HiddenSize),
+				rnn.NewNetworkBlock(neuralnet.Network{
+					neuralnet.NewDenseLayer(HiddenSize, 1),
+				}, 0),
+			},
+		},
+		Generator: &rnn.BlockSeqFunc{
+			B: rnn.StackedBlock{
+				rnn.NewLSTM(RandCount, HiddenSize),
+				rnn.NewNetworkBlock(neuralnet.Network{
+					neuralnet.NewDenseLayer(HiddenSize, columns),
+				}, 0),
+			},
+		},
+		RandomSize: RandCount,
+	}
+}
+
+func formatRow(row linalg.Vector) string {
+	fields := make([]string, len(row))
+	for i, x := range row {
+		fields[i] = strconv.FormatFloat(x, 'g', -1, 64)
+	}
+	return strings.Join(fields, ",")
+}
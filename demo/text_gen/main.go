@@ -2,15 +2,16 @@ package main
 
 import (
 	"fmt"
+	"image/png"
 	"io/ioutil"
 	"log"
-	"math"
 	"math/rand"
 	"os"
+	"path/filepath"
 	"time"
 
 	"github.com/unixpickle/gans"
-	"github.com/unixpickle/num-analysis/linalg"
+	"github.com/unixpickle/gans/eval"
 	"github.com/unixpickle/sgd"
 	"github.com/unixpickle/weakai/neuralnet"
 	"github.com/unixpickle/weakai/rnn"
@@ -24,6 +25,11 @@ const (
 	GenAtEnd       = 10
 	GenTemperature = 1
 
+	HeldOutCount = 200
+	MetricCount  = 50
+
+	SheetWidth = 800
+
 	BatchSize = 64
 )
 
@@ -31,12 +37,28 @@ var StepSize = 1e-3
 
 func main() {
 	rand.Seed(time.Now().UnixNano())
-	if len(os.Args) != 3 {
-		fmt.Fprintln(os.Stderr, "Usage:", os.Args[0], "corpus.txt model_file")
+	if len(os.Args) != 3 && len(os.Args) != 4 {
+		fmt.Fprintln(os.Stderr, "Usage:", os.Args[0],
+			"corpus.txt model_file [sheet.html|sheet.png]")
 		os.Exit(1)
 	}
-	samples := ReadSampleSet(os.Args[1])
+	opts := gans.DefaultCorpusOptions()
+	opts.Filter = gans.LettersAndSpaces
+	samples, err := gans.ReadTextCorpus(os.Args[1], gans.ByteVocab{}, opts)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Read sentences failed:", err)
+		os.Exit(1)
+	}
+	heldOut := samples.HoldOut(HeldOutCount)
 	model := readOrCreateModel(os.Args[2])
+	if model.Vocab == nil {
+		model.Vocab = samples.Vocab
+	}
+	metrics := &eval.TextMetrics{
+		Corpus:     samples.Sentences,
+		References: heldOut,
+	}
+	batchesPerEpoch := (samples.Len() + BatchSize - 1) / BatchSize
 
 	model.DiscIterations = 1
 	model.GenIterations = 1
@@ -55,6 +77,14 @@ func main() {
 		lastBatch = s.Copy()
 		log.Printf("iteration %d: disc=%f gen=%f last_disc=%f last_gen=%f", iteration,
 			model.DiscCost(s).Output()[0], model.GenReward(s), lastReal, lastGen)
+		if iteration%batchesPerEpoch == 0 {
+			generated := make([]string, MetricCount)
+			for i := range generated {
+				generated[i] = model.GenerateText(MaxLen)
+			}
+			log.Printf("epoch %d: %s", iteration/batchesPerEpoch,
+				metrics.Evaluate(generated))
+		}
 		iteration++
 		return true
 	})
@@ -71,9 +101,35 @@ func main() {
 	}
 
 	log.Println("Generating sentences...")
+	var sheet []gans.TextSample
 	for i := 0; i < GenAtEnd; i++ {
-		fmt.Println(generateSentence(model))
+		symbols := model.Generate(MaxLen)
+		fmt.Println(gans.DecodeText(model.Vocab, symbols))
+		sheet = append(sheet, gans.TextSample{
+			Symbols: symbols,
+			Scores:  model.Scores(gans.OneHotSeq(symbols, model.Vocab.Size())),
+		})
+	}
+
+	if len(os.Args) == 4 {
+		log.Println("Writing contact sheet...")
+		if err := writeSheet(os.Args[3], sheet, model.Vocab); err != nil {
+			fmt.Fprintln(os.Stderr, "Write sheet failed:", err)
+			os.Exit(1)
+		}
+	}
+}
+
+func writeSheet(path string, sheet []gans.TextSample, vocab gans.Vocabulary) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
 	}
+	defer f.Close()
+	if filepath.Ext(path) == ".png" {
+		return png.Encode(f, gans.TextSheetImage(sheet, vocab.Decode, SheetWidth))
+	}
+	return gans.TextSheetHTML(f, sheet, vocab.Decode)
 }
 
 func readOrCreateModel(path string) *gans.Recurrent {
@@ -112,34 +168,7 @@ func readOrCreateModel(path string) *gans.Recurrent {
 		},
 		RandomSize:     RandCount,
 		DiscountFactor: 0.8,
+		Vocab:          gans.ByteVocab{},
 	}
 	return rec
 }
-
-func generateSentence(model *gans.Recurrent) string {
-	var res string
-	runner := &rnn.Runner{Block: model.Generator.(*rnn.BlockSeqFunc).B}
-
-	for i := 0; i < MaxLen; i++ {
-		input := make(linalg.Vector, model.RandomSize)
-		for j := range input {
-			input[j] = rand.NormFloat64()
-		}
-
-		outVec := runner.StepTime(input)
-		res += string(byte(randomSample(outVec[:CharCount])))
-	}
-
-	return res
-}
-
-func randomSample(probs linalg.Vector) int {
-	num := rand.Float64()
-	for i, x := range probs {
-		num -= math.Exp(x)
-		if num < 0 {
-			return i
-		}
-	}
-	return len(probs) - 1
-}
@@ -0,0 +1,102 @@
+package gans
+
+import (
+	"github.com/unixpickle/autofunc"
+	"github.com/unixpickle/autofunc/seqfunc"
+	"github.com/unixpickle/num-analysis/linalg"
+)
+
+// DiscMode determines how a recurrent discriminator's
+// outputs are turned into judgments.
+type DiscMode int
+
+const (
+	// PerStepDisc judges a sequence at every timestep.
+	PerStepDisc DiscMode = iota
+
+	// FinalDisc judges a sequence once, using the
+	// discriminator's output at the last timestep.
+	FinalDisc
+
+	// MeanDisc judges a sequence once, using the mean of
+	// the discriminator's outputs over time.
+	MeanDisc
+)
+
+// pool reduces each output sequence of a discriminator to
+// a single timestep, unless the mode is PerStepDisc.
+func (d DiscMode) pool(out seqfunc.Result) seqfunc.Result {
+	if d == PerStepDisc {
+		return out
+	}
+	res := &pooledSeqs{Input: out, Mode: d}
+	for _, seq := range out.OutputSeqs() {
+		if len(seq) == 0 {
+			res.Pooled = append(res.Pooled, nil)
+			continue
+		}
+		var pooled linalg.Vector
+		if d == FinalDisc {
+			pooled = append(linalg.Vector{}, seq[len(seq)-1]...)
+		} else {
+			pooled = make(linalg.Vector, len(seq[0]))
+			for _, vec := range seq {
+				for i, x := range vec {
+					pooled[i] += x / float64(len(seq))
+				}
+			}
+		}
+		res.Pooled = append(res.Pooled, []linalg.Vector{pooled})
+	}
+	return res
+}
+
+// stepRewards spreads pooled discriminator outputs back
+// over the timesteps of sequences of the given lengths.
+// In pooled modes, the judgment is assigned to the last
+// timestep and every other timestep gets a reward of 0.
+func (d DiscMode) stepRewards(pooled [][]linalg.Vector, lengths []int) [][]float64 {
+	res := make([][]float64, len(pooled))
+	for i, seq := range pooled {
+		res[i] = make([]float64, lengths[i])
+		if d == PerStepDisc {
+			for j, vec := range seq {
+				res[i][j] = vec[0]
+			}
+		} else if len(seq) > 0 {
+			res[i][lengths[i]-1] = seq[0][0]
+		}
+	}
+	return res
+}
+
+type pooledSeqs struct {
+	Input  seqfunc.Result
+	Mode   DiscMode
+	Pooled [][]linalg.Vector
+}
+
+func (p *pooledSeqs) OutputSeqs() [][]linalg.Vector {
+	return p.Pooled
+}
+
+func (p *pooledSeqs) PropagateGradient(upstream [][]linalg.Vector, grad autofunc.Gradient) {
+	downstream := make([][]linalg.Vector, len(upstream))
+	for i, inSeq := range p.Input.OutputSeqs() {
+		downstream[i] = make([]linalg.Vector, len(inSeq))
+		for t, vec := range inSeq {
+			downstream[i][t] = make(linalg.Vector, len(vec))
+			if len(upstream[i]) == 0 {
+				continue
+			}
+			if p.Mode == MeanDisc {
+				for j, x := range upstream[i][0] {
+					downstream[i][t][j] = x / float64(len(inSeq))
+				}
+			} else if t == len(inSeq)-1 {
+				copy(downstream[i][t], upstream[i][0])
+			}
+		}
+	}
+	p.Input.PropagateGradient(downstream, grad)
+}
@@ -0,0 +1,77 @@
+package gans
+
+import (
+	"testing"
+
+	"github.com/unixpickle/autofunc"
+	"github.com/unixpickle/num-analysis/linalg"
+)
+
+func TestDiscModePool(t *testing.T) {
+	seqs := [][]linalg.Vector{{{1}, {2}, {6}}, {}, {{-4}}}
+	expected := map[DiscMode][][]linalg.Vector{
+		PerStepDisc: seqs,
+		FinalDisc:   {{{6}}, nil, {{-4}}},
+		MeanDisc:    {{{3}}, nil, {{-4}}},
+	}
+	for mode, expSeqs := range expected {
+		actual := mode.pool(&recordedSeqs{Output: seqs}).OutputSeqs()
+		if len(actual) != len(expSeqs) {
+			t.Errorf("mode %d: expected %d seqs but got %d", mode, len(expSeqs),
+				len(actual))
+			continue
+		}
+		for i, seq := range expSeqs {
+			if len(actual[i]) != len(seq) {
+				t.Errorf("mode %d seq %d: expected %v but got %v", mode, i, seq,
+					actual[i])
+				continue
+			}
+			for j, vec := range seq {
+				if !vecsEqual(vec, actual[i][j]) {
+					t.Errorf("mode %d seq %d: expected %v but got %v", mode, i,
+						seq, actual[i])
+				}
+			}
+		}
+	}
+}
+
+func TestDiscModePoolGradient(t *testing.T) {
+	for _, mode := range []DiscMode{FinalDisc, MeanDisc} {
+		input := &recordedSeqs{Output: randomSeqs([]int{4, 0, 1}, 2)}
+		pooled := mode.pool(input)
+		upstream := randomSeqs([]int{1, 0, 1}, 2)
+		pooled.PropagateGradient(upstream, autofunc.Gradient{})
+
+		// Pooling is linear, so <P*x, y> must equal <x, P^T*y>.
+		expected := seqsDot(pooled.OutputSeqs(), upstream)
+		actual := seqsDot(input.Output, input.Upstream)
+		if !vecsEqual(linalg.Vector{expected}, linalg.Vector{actual}) {
+			t.Errorf("mode %d: expected %f but got %f", mode, expected, actual)
+		}
+	}
+}
+
+func TestDiscModeStepRewards(t *testing.T) {
+	pooled := [][]linalg.Vector{{{0.5}}, nil}
+	rewards := FinalDisc.stepRewards(pooled, []int{3, 0})
+	if !vecsEqual(rewards[0], []float64{0, 0, 0.5}) || len(rewards[1]) != 0 {
+		t.Errorf("unexpected rewards: %v", rewards)
+	}
+}
+
+// recordedSeqs is a seqfunc.Result which records the
+// gradient that is propagated through it.
+type recordedSeqs struct {
+	Output   [][]linalg.Vector
+	Upstream [][]linalg.Vector
+}
+
+func (r *recordedSeqs) OutputSeqs() [][]linalg.Vector {
+	return r.Output
+}
+
+func (r *recordedSeqs) PropagateGradient(upstream [][]linalg.Vector, grad autofunc.Gradient) {
+	r.Upstream = upstream
+}
@@ -0,0 +1,158 @@
+package gans
+
+import (
+	"math/rand"
+
+	"github.com/unixpickle/autofunc"
+	"github.com/unixpickle/num-analysis/linalg"
+	"github.com/unixpickle/serializer"
+)
+
+func init() {
+	var d DiscNoise
+	serializer.RegisterTypedDeserializer(d.SerializerType(), DeserializeDiscNoise)
+}
+
+// DiscNoise configures label smoothing, label flipping,
+// and instance noise for discriminator training.
+//
+// A nil or zero DiscNoise leaves training unchanged.
+type DiscNoise struct {
+	// RealTarget is the discriminator's target for real
+	// samples, allowing for one-sided label smoothing.
+	// A value of 0 is treated as 1.
+	RealTarget float64
+
+	// FlipProb is the probability that any given sample
+	// is presented with the opposite label.
+	FlipProb float64
+
+	// InstanceNoise is the initial standard deviation of
+	// the Gaussian noise added to real and generated
+	// discriminator inputs.
+	InstanceNoise float64
+
+	// AnnealSteps is the number of steps over which the
+	// instance noise decays linearly to zero.
+	// If it is 0, the noise never decays.
+	AnnealSteps int
+
+	// Step is the number of discriminator steps taken so
+	// far, used for annealing.
+	Step int
+}
+
+// DeserializeDiscNoise deserializes a DiscNoise.
+func DeserializeDiscNoise(d []byte) (*DiscNoise, error) {
+	var target, flip, noise serializer.Float64
+	var anneal, step serializer.Int
+	err := serializer.DeserializeAny(d, &target, &flip, &noise, &anneal, &step)
+	if err != nil {
+		return nil, err
+	}
+	return &DiscNoise{
+		RealTarget:    float64(target),
+		FlipProb:      float64(flip),
+		InstanceNoise: float64(noise),
+		AnnealSteps:   int(anneal),
+		Step:          int(step),
+	}, nil
+}
+
+// NoiseStddev returns the current standard deviation of
+// the instance noise, taking annealing into account.
+func (d *DiscNoise) NoiseStddev() float64 {
+	if d == nil || d.InstanceNoise == 0 {
+		return 0
+	}
+	if d.AnnealSteps == 0 {
+		return d.InstanceNoise
+	}
+	if d.Step >= d.AnnealSteps {
+		return 0
+	}
+	return d.InstanceNoise * (1 - float64(d.Step)/float64(d.AnnealSteps))
+}
+
+// SerializerType returns the unique ID used to serialize
+// a DiscNoise with the serializer package.
+func (d *DiscNoise) SerializerType() string {
+	return "github.com/unixpickle/gans.DiscNoise"
+}
+
+// Serialize serializes the configuration.
+func (d *DiscNoise) Serialize() ([]byte, error) {
+	return serializer.SerializeAny(
+		serializer.Float64(d.RealTarget),
+		serializer.Float64(d.FlipProb),
+		serializer.Float64(d.InstanceNoise),
+		serializer.Int(d.AnnealSteps),
+		serializer.Int(d.Step),
+	)
+}
+
+func (d *DiscNoise) realTarget() float64 {
+	if d == nil || d.RealTarget == 0 {
+		return 1
+	}
+	return d.RealTarget
+}
+
+// flip randomly decides whether or not to flip a label.
+func (d *DiscNoise) flip() bool {
+	return d != nil && d.FlipProb > 0 && rand.Float64() < d.FlipProb
+}
+
+// target returns the (possibly flipped) target for a
+// real or generated sample.
+func (d *DiscNoise) target(real bool) float64 {
+	if d.flip() {
+		real = !real
+	}
+	if real {
+		return d.realTarget()
+	}
+	return 0
+}
+
+// targets returns a vector of n targets.
+func (d *DiscNoise) targets(n int, real bool) linalg.Vector {
+	res := make(linalg.Vector, n)
+	for i := range res {
+		res[i] = d.target(real)
+	}
+	return res
+}
+
+// noisyVec returns a copy of v with instance noise, or v
+// itself if there is no noise.
+func (d *DiscNoise) noisyVec(v linalg.Vector) linalg.Vector {
+	stddev := d.NoiseStddev()
+	if stddev == 0 {
+		return v
+	}
+	res := make(linalg.Vector, len(v))
+	for i, x := range v {
+		res[i] = x + rand.NormFloat64()*stddev
+	}
+	return res
+}
+
+// noisyResult adds constant instance noise to r.
+func (d *DiscNoise) noisyResult(r autofunc.Result) autofunc.Result {
+	stddev := d.NoiseStddev()
+	if stddev == 0 {
+		return r
+	}
+	noise := make(linalg.Vector, len(r.Output()))
+	for i := range noise {
+		noise[i] = rand.NormFloat64() * stddev
+	}
+	return autofunc.Add(r, &autofunc.Variable{Vector: noise})
+}
+
+func (d *DiscNoise) step() {
+	if d != nil {
+		d.Step++
+	}
+}
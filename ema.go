@@ -0,0 +1,28 @@
+package gans
+
+import (
+	"github.com/unixpickle/weakai/neuralnet"
+)
+
+// copyNetwork creates a deep copy of a network by
+// serializing and deserializing it.
+func copyNetwork(n neuralnet.Network) (neuralnet.Network, error) {
+	data, err := n.Serialize()
+	if err != nil {
+		return nil, err
+	}
+	return neuralnet.DeserializeNetwork(data)
+}
+
+// updateEMA moves the parameters of avg towards the
+// parameters of current.
+// Both networks must have the same structure.
+func updateEMA(avg, current neuralnet.Network, decay float64) {
+	avgParams := avg.Parameters()
+	for i, param := range current.Parameters() {
+		avgVec := avgParams[i].Vector
+		for j, x := range param.Vector {
+			avgVec[j] = decay*avgVec[j] + (1-decay)*x
+		}
+	}
+}
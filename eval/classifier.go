@@ -0,0 +1,131 @@
+// Package eval implements quantitative measures of the
+// quality of samples from generative models.
+package eval
+
+import (
+	"errors"
+	"math"
+
+	"github.com/unixpickle/autofunc"
+	"github.com/unixpickle/num-analysis/linalg"
+	"github.com/unixpickle/weakai/neuralnet"
+)
+
+// Classifier wraps a pretrained classifier network which
+// is used to evaluate samples.
+type Classifier struct {
+	// Network maps samples to log probabilities, for
+	// example by ending with a neuralnet.LogSoftmaxLayer.
+	Network neuralnet.Network
+
+	// FeatureLayers is the number of layers from Network
+	// used to produce feature vectors for the Fréchet
+	// distance.
+	FeatureLayers int
+}
+
+// LogProbs computes the classifier's log probabilities
+// for every sample.
+func (c *Classifier) LogProbs(samples []linalg.Vector) []linalg.Vector {
+	return applyAll(c.Network, samples)
+}
+
+// Features computes the classifier's feature vectors for
+// every sample.
+func (c *Classifier) Features(samples []linalg.Vector) []linalg.Vector {
+	return applyAll(c.Network[:c.FeatureLayers], samples)
+}
+
+// InceptionScore computes the Inception Score of the
+// samples, exp(E[KL(p(y|x) || p(y))]).
+//
+// The samples are divided into the given number of splits
+// and the mean and standard deviation of the scores for
+// every split are returned.
+func (c *Classifier) InceptionScore(samples []linalg.Vector, splits int) (mean,
+	stddev float64) {
+	if splits < 1 {
+		splits = 1
+	}
+	logProbs := c.LogProbs(samples)
+	scores := make([]float64, splits)
+	for i := range scores {
+		start := i * len(logProbs) / splits
+		end := (i + 1) * len(logProbs) / splits
+		scores[i] = inceptionScore(logProbs[start:end])
+	}
+	for _, s := range scores {
+		mean += s
+	}
+	mean /= float64(splits)
+	for _, s := range scores {
+		stddev += (s - mean) * (s - mean)
+	}
+	stddev = math.Sqrt(stddev / float64(splits))
+	return
+}
+
+// FrechetDistance computes the Fréchet distance between
+// the distributions of the classifier's features on real
+// and generated samples.
+func (c *Classifier) FrechetDistance(real, generated []linalg.Vector) (float64, error) {
+	return FrechetDistance(c.Features(real), c.Features(generated))
+}
+
+// FrechetDistance computes the Fréchet distance between
+// two Gaussians fit to two sets of feature vectors:
+//
+//	|m1-m2|^2 + tr(C1 + C2 - 2*sqrt(C1*C2))
+//
+// An error is returned if either set is empty or if the
+// features have different sizes.
+func FrechetDistance(features1, features2 []linalg.Vector) (float64, error) {
+	mean1, cov1, err := meanCovariance(features1)
+	if err != nil {
+		return 0, err
+	}
+	mean2, cov2, err := meanCovariance(features2)
+	if err != nil {
+		return 0, err
+	}
+	if len(mean1) != len(mean2) {
+		return 0, errors.New("mismatched feature sizes")
+	}
+	var meanDist float64
+	for i, x := range mean1 {
+		meanDist += (x - mean2[i]) * (x - mean2[i])
+	}
+	return meanDist + cov1.trace() + cov2.trace() - 2*traceSqrtProduct(cov1, cov2), nil
+}
+
+func inceptionScore(logProbs []linalg.Vector) float64 {
+	if len(logProbs) == 0 {
+		return 0
+	}
+	marginal := make(linalg.Vector, len(logProbs[0]))
+	for _, lp := range logProbs {
+		for i, x := range lp {
+			marginal[i] += math.Exp(x)
+		}
+	}
+	marginal.Scale(1 / float64(len(logProbs)))
+
+	var meanKL float64
+	for _, lp := range logProbs {
+		for i, x := range lp {
+			if marginal[i] > 0 {
+				meanKL += math.Exp(x) * (x - math.Log(marginal[i]))
+			}
+		}
+	}
+	meanKL /= float64(len(logProbs))
+	return math.Exp(meanKL)
+}
+
+func applyAll(net neuralnet.Network, samples []linalg.Vector) []linalg.Vector {
+	res := make([]linalg.Vector, len(samples))
+	for i, sample := range samples {
+		res[i] = net.Apply(&autofunc.Variable{Vector: sample}).Output()
+	}
+	return res
+}
@@ -0,0 +1,204 @@
+package eval
+
+import (
+	"errors"
+	"math"
+
+	"github.com/unixpickle/num-analysis/linalg"
+)
+
+const (
+	jacobiMaxSweeps = 100
+	jacobiEpsilon   = 1e-12
+)
+
+// matrix is a square, row-major matrix.
+type matrix struct {
+	n    int
+	data []float64
+}
+
+func newMatrix(n int) *matrix {
+	return &matrix{n: n, data: make([]float64, n*n)}
+}
+
+func identityMatrix(n int) *matrix {
+	res := newMatrix(n)
+	for i := 0; i < n; i++ {
+		res.set(i, i, 1)
+	}
+	return res
+}
+
+func (m *matrix) at(i, j int) float64 {
+	return m.data[i*m.n+j]
+}
+
+func (m *matrix) set(i, j int, x float64) {
+	m.data[i*m.n+j] = x
+}
+
+func (m *matrix) copy() *matrix {
+	return &matrix{n: m.n, data: append([]float64{}, m.data...)}
+}
+
+func (m *matrix) mul(m1 *matrix) *matrix {
+	res := newMatrix(m.n)
+	for i := 0; i < m.n; i++ {
+		for k := 0; k < m.n; k++ {
+			x := m.at(i, k)
+			if x == 0 {
+				continue
+			}
+			for j := 0; j < m.n; j++ {
+				res.data[i*m.n+j] += x * m1.at(k, j)
+			}
+		}
+	}
+	return res
+}
+
+func (m *matrix) trace() float64 {
+	var res float64
+	for i := 0; i < m.n; i++ {
+		res += m.at(i, i)
+	}
+	return res
+}
+
+// meanCovariance computes the mean and the (unbiased)
+// covariance matrix of a list of vectors.
+func meanCovariance(vecs []linalg.Vector) (linalg.Vector, *matrix, error) {
+	if len(vecs) == 0 {
+		return nil, nil, errors.New("no vectors for covariance")
+	}
+	n := len(vecs[0])
+	mean := make(linalg.Vector, n)
+	for _, v := range vecs {
+		for i, x := range v {
+			mean[i] += x
+		}
+	}
+	mean.Scale(1 / float64(len(vecs)))
+
+	cov := newMatrix(n)
+	for _, v := range vecs {
+		for i := 0; i < n; i++ {
+			di := v[i] - mean[i]
+			for j := i; j < n; j++ {
+				cov.data[i*n+j] += di * (v[j] - mean[j])
+			}
+		}
+	}
+	denom := float64(len(vecs) - 1)
+	if denom < 1 {
+		denom = 1
+	}
+	for i := 0; i < n; i++ {
+		for j := i; j < n; j++ {
+			x := cov.at(i, j) / denom
+			cov.set(i, j, x)
+			cov.set(j, i, x)
+		}
+	}
+	return mean, cov, nil
+}
+
+// symmetricEigen computes the eigenvalues and the
+// eigenvectors (as columns) of a symmetric matrix using
+// the cyclic Jacobi method.
+func symmetricEigen(m *matrix) ([]float64, *matrix) {
+	a := m.copy()
+	v := identityMatrix(m.n)
+	n := m.n
+
+	var scale float64
+	for _, x := range a.data {
+		scale += x * x
+	}
+
+	for sweep := 0; sweep < jacobiMaxSweeps; sweep++ {
+		var off float64
+		for p := 0; p < n; p++ {
+			for q := p + 1; q < n; q++ {
+				off += a.at(p, q) * a.at(p, q)
+			}
+		}
+		if off <= jacobiEpsilon*jacobiEpsilon*scale {
+			break
+		}
+		for p := 0; p < n; p++ {
+			for q := p + 1; q < n; q++ {
+				apq := a.at(p, q)
+				if apq == 0 {
+					continue
+				}
+				theta := (a.at(q, q) - a.at(p, p)) / (2 * apq)
+				t := 1 / (math.Abs(theta) + math.Sqrt(theta*theta+1))
+				if theta < 0 {
+					t = -t
+				}
+				c := 1 / math.Sqrt(t*t+1)
+				s := t * c
+				for k := 0; k < n; k++ {
+					akp, akq := a.at(k, p), a.at(k, q)
+					a.set(k, p, c*akp-s*akq)
+					a.set(k, q, s*akp+c*akq)
+				}
+				for k := 0; k < n; k++ {
+					apk, aqk := a.at(p, k), a.at(q, k)
+					a.set(p, k, c*apk-s*aqk)
+					a.set(q, k, s*apk+c*aqk)
+				}
+				for k := 0; k < n; k++ {
+					vkp, vkq := v.at(k, p), v.at(k, q)
+					v.set(k, p, c*vkp-s*vkq)
+					v.set(k, q, s*vkp+c*vkq)
+				}
+			}
+		}
+	}
+
+	vals := make([]float64, n)
+	for i := range vals {
+		vals[i] = a.at(i, i)
+	}
+	return vals, v
+}
+
+// sqrtSymmetric computes the principal square root of a
+// symmetric positive semi-definite matrix.
+// Slightly negative eigenvalues, which arise from
+// numerical error, are treated as zero.
+func sqrtSymmetric(m *matrix) *matrix {
+	vals, vecs := symmetricEigen(m)
+	res := newMatrix(m.n)
+	for k, val := range vals {
+		root := math.Sqrt(math.Max(val, 0))
+		if root == 0 {
+			continue
+		}
+		for i := 0; i < m.n; i++ {
+			x := vecs.at(i, k) * root
+			for j := 0; j < m.n; j++ {
+				res.data[i*m.n+j] += x * vecs.at(j, k)
+			}
+		}
+	}
+	return res
+}
+
+// traceSqrtProduct computes tr(sqrt(m1*m2)) for symmetric
+// positive semi-definite matrices m1 and m2.
+//
+// Since sqrt(m1)*m2*sqrt(m1) is similar to m1*m2 and is
+// itself symmetric, its eigenvalues can be found stably.
+func traceSqrtProduct(m1, m2 *matrix) float64 {
+	root := sqrtSymmetric(m1)
+	vals, _ := symmetricEigen(root.mul(m2).mul(root))
+	var res float64
+	for _, val := range vals {
+		res += math.Sqrt(math.Max(val, 0))
+	}
+	return res
+}
@@ -0,0 +1,102 @@
+package eval
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/unixpickle/num-analysis/linalg"
+)
+
+func TestSqrtSymmetric(t *testing.T) {
+	for _, n := range []int{1, 2, 5, 10} {
+		m := randomCovariance(n)
+		root := sqrtSymmetric(m)
+		product := root.mul(root)
+		for i, x := range m.data {
+			if math.Abs(product.data[i]-x) > 1e-6 {
+				t.Errorf("size %d: entry %d should be %f but got %f", n, i, x,
+					product.data[i])
+				break
+			}
+		}
+		for i := 0; i < n; i++ {
+			for j := 0; j < i; j++ {
+				if math.Abs(root.at(i, j)-root.at(j, i)) > 1e-8 {
+					t.Errorf("size %d: root is not symmetric", n)
+				}
+			}
+		}
+	}
+}
+
+func TestSqrtSymmetricSingular(t *testing.T) {
+	// A rank-one matrix has eigenvalues which may come out
+	// slightly negative.
+	v := []float64{1, -2, 3}
+	m := newMatrix(3)
+	for i, x := range v {
+		for j, y := range v {
+			m.set(i, j, x*y)
+		}
+	}
+	root := sqrtSymmetric(m)
+	scale := 1 / math.Sqrt(1+4+9)
+	for i, x := range v {
+		for j, y := range v {
+			if math.Abs(root.at(i, j)-x*y*scale) > 1e-6 {
+				t.Fatalf("entry (%d, %d) should be %f but got %f", i, j,
+					x*y*scale, root.at(i, j))
+			}
+		}
+	}
+}
+
+func TestFrechetDistance(t *testing.T) {
+	var features []linalg.Vector
+	for i := 0; i < 200; i++ {
+		features = append(features, linalg.Vector{rand.NormFloat64(),
+			rand.NormFloat64() * 2, rand.NormFloat64()})
+	}
+	dist, err := FrechetDistance(features, features)
+	if err != nil {
+		t.Fatal(err)
+	} else if math.Abs(dist) > 1e-6 {
+		t.Errorf("distance to self should be 0 but got %f", dist)
+	}
+
+	// Shifting every feature by a vector adds the squared
+	// norm of the shift.
+	var shifted []linalg.Vector
+	for _, f := range features {
+		shifted = append(shifted, linalg.Vector{f[0] + 1, f[1] - 2, f[2]})
+	}
+	dist, err = FrechetDistance(features, shifted)
+	if err != nil {
+		t.Fatal(err)
+	} else if math.Abs(dist-5) > 1e-6 {
+		t.Errorf("distance should be 5 but got %f", dist)
+	}
+
+	if _, err := FrechetDistance(nil, features); err == nil {
+		t.Error("expected error for empty features")
+	}
+}
+
+func randomCovariance(n int) *matrix {
+	a := newMatrix(n)
+	for i := range a.data {
+		a.data[i] = rand.NormFloat64()
+	}
+	res := newMatrix(n)
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			var sum float64
+			for k := 0; k < n; k++ {
+				sum += a.at(i, k) * a.at(j, k)
+			}
+			res.set(i, j, sum)
+		}
+	}
+	return res
+}
@@ -0,0 +1,170 @@
+package eval
+
+import (
+	"math"
+	"math/rand"
+
+	"github.com/unixpickle/num-analysis/linalg"
+	"github.com/unixpickle/sgd"
+	"github.com/unixpickle/weakai/neuralnet"
+)
+
+// QualityStddevs is the number of standard deviations
+// from its closest mode within which a sample is
+// considered to be high-quality.
+const QualityStddevs = 3
+
+// Mixture is an equally-weighted mixture of isotropic
+// Gaussians, typically in two dimensions.
+// It is useful for quickly checking whether a trainer
+// suffers from mode collapse.
+type Mixture struct {
+	Means  []linalg.Vector
+	Stddev float64
+}
+
+// RingMixture creates a mixture of count Gaussians whose
+// means are evenly spaced around a circle.
+func RingMixture(count int, radius, stddev float64) *Mixture {
+	res := &Mixture{Stddev: stddev}
+	for i := 0; i < count; i++ {
+		angle := 2 * math.Pi * float64(i) / float64(count)
+		res.Means = append(res.Means, linalg.Vector{
+			radius * math.Cos(angle),
+			radius * math.Sin(angle),
+		})
+	}
+	return res
+}
+
+// GridMixture creates a mixture of size*size Gaussians
+// whose means form a grid centered at the origin.
+func GridMixture(size int, spacing, stddev float64) *Mixture {
+	res := &Mixture{Stddev: stddev}
+	offset := spacing * float64(size-1) / 2
+	for i := 0; i < size; i++ {
+		for j := 0; j < size; j++ {
+			res.Means = append(res.Means, linalg.Vector{
+				float64(i)*spacing - offset,
+				float64(j)*spacing - offset,
+			})
+		}
+	}
+	return res
+}
+
+// Sample draws a vector from the mixture and returns it
+// along with the index of the mode it came from.
+func (m *Mixture) Sample() (linalg.Vector, int) {
+	mode := rand.Intn(len(m.Means))
+	mean := m.Means[mode]
+	res := make(linalg.Vector, len(mean))
+	for i, x := range mean {
+		res[i] = x + rand.NormFloat64()*m.Stddev
+	}
+	return res, mode
+}
+
+// SampleSet draws n samples from the mixture.
+func (m *Mixture) SampleSet(n int) *MixtureSet {
+	res := &MixtureSet{Samples: make([]neuralnet.VectorSample, n)}
+	for i := range res.Samples {
+		vec, mode := m.Sample()
+		label := make(linalg.Vector, len(m.Means))
+		label[mode] = 1
+		res.Samples[i] = neuralnet.VectorSample{Input: vec, Output: label}
+	}
+	return res
+}
+
+// Coverage measures how well a set of generated samples
+// covers the mixture's modes.
+//
+// A sample is high-quality if it is within QualityStddevs
+// standard deviations of its closest mode.
+// A mode is covered if at least minCount high-quality
+// samples are closest to it.
+func (m *Mixture) Coverage(samples []linalg.Vector, minCount int) *Coverage {
+	res := &Coverage{ModeCounts: make([]int, len(m.Means))}
+	if len(samples) == 0 {
+		return res
+	}
+	maxDist := QualityStddevs * m.Stddev
+	var highQuality int
+	for _, sample := range samples {
+		mode, dist := m.closestMode(sample)
+		if dist <= maxDist {
+			highQuality++
+			res.ModeCounts[mode]++
+		}
+	}
+	for _, count := range res.ModeCounts {
+		if count >= minCount {
+			res.ModesCovered++
+		}
+	}
+	res.HighQuality = float64(highQuality) / float64(len(samples))
+	return res
+}
+
+func (m *Mixture) closestMode(v linalg.Vector) (int, float64) {
+	var bestMode int
+	bestDist := math.Inf(1)
+	for i, mean := range m.Means {
+		var sum float64
+		for j, x := range mean {
+			sum += (x - v[j]) * (x - v[j])
+		}
+		if sum < bestDist {
+			bestDist = sum
+			bestMode = i
+		}
+	}
+	return bestMode, math.Sqrt(bestDist)
+}
+
+// Coverage stores the results of Mixture.Coverage.
+type Coverage struct {
+	// ModesCovered is the number of covered modes.
+	ModesCovered int
+
+	// HighQuality is the fraction of samples which are
+	// high-quality.
+	HighQuality float64
+
+	// ModeCounts stores the number of high-quality samples
+	// assigned to each mode.
+	ModeCounts []int
+}
+
+// MixtureSet is a set of samples from a Mixture.
+// Every sample is a neuralnet.VectorSample whose output
+// is a one-hot vector indicating its mode.
+type MixtureSet struct {
+	Samples []neuralnet.VectorSample
+}
+
+// Len returns the number of samples.
+func (m *MixtureSet) Len() int {
+	return len(m.Samples)
+}
+
+// Swap swaps two samples.
+func (m *MixtureSet) Swap(i, j int) {
+	m.Samples[i], m.Samples[j] = m.Samples[j], m.Samples[i]
+}
+
+// GetSample returns the sample at the given index.
+func (m *MixtureSet) GetSample(i int) interface{} {
+	return m.Samples[i]
+}
+
+// Copy creates a shallow copy of the set.
+func (m *MixtureSet) Copy() sgd.SampleSet {
+	return &MixtureSet{Samples: append([]neuralnet.VectorSample{}, m.Samples...)}
+}
+
+// Subset returns a subset of the set.
+func (m *MixtureSet) Subset(start, end int) sgd.SampleSet {
+	return &MixtureSet{Samples: m.Samples[start:end]}
+}
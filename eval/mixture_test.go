@@ -0,0 +1,107 @@
+package eval
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/unixpickle/gans"
+	"github.com/unixpickle/num-analysis/linalg"
+	"github.com/unixpickle/sgd"
+	"github.com/unixpickle/weakai/neuralnet"
+)
+
+func TestCoverage(t *testing.T) {
+	mixture := RingMixture(4, 1, 0.1)
+	var samples []linalg.Vector
+	for i := 0; i < 3; i++ {
+		samples = append(samples, mixture.Means[0], mixture.Means[1])
+	}
+	samples = append(samples, mixture.Means[2], linalg.Vector{10, 10})
+
+	coverage := mixture.Coverage(samples, 2)
+	if coverage.ModesCovered != 2 {
+		t.Errorf("expected 2 modes covered but got %d", coverage.ModesCovered)
+	}
+	if coverage.HighQuality != 7.0/8 {
+		t.Errorf("expected high quality 0.875 but got %f", coverage.HighQuality)
+	}
+	expectedCounts := []int{3, 3, 1, 0}
+	for i, count := range expectedCounts {
+		if coverage.ModeCounts[i] != count {
+			t.Errorf("mode %d: expected count %d but got %d", i, count,
+				coverage.ModeCounts[i])
+		}
+	}
+}
+
+// TestFMRingMixture checks that the FM trainer learns to
+// cover every mode of a mixture of Gaussians.
+// It trains a GAN, so it is skipped in short mode.
+func TestFMRingMixture(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping GAN training in short mode")
+	}
+	const (
+		modeCount  = 4
+		iterations = 3000
+		evalCount  = 2000
+	)
+	mixture := RingMixture(modeCount, 1, 0.1)
+	samples := sampleMixture(mixture, 5000, rand.New(rand.NewSource(1337)))
+	discrim := neuralnet.Network{
+		neuralnet.NewDenseLayer(2, 64),
+		neuralnet.ReLU{},
+		neuralnet.NewDenseLayer(64, 64),
+		neuralnet.ReLU{},
+		neuralnet.NewDenseLayer(64, 1),
+	}
+	gen := neuralnet.Network{
+		neuralnet.NewDenseLayer(8, 64),
+		neuralnet.ReLU{},
+		neuralnet.NewDenseLayer(64, 64),
+		neuralnet.ReLU{},
+		neuralnet.NewDenseLayer(64, 2),
+	}
+	fm := &gans.FM{
+		Discriminator: discrim,
+		FeatureLayers: len(discrim) - 1,
+		Generator:     gen,
+		RandomSize:    8,
+	}
+
+	var iteration int
+	sgd.SGDMini(fm, samples, 0.001, 64, func(s sgd.SampleSet) bool {
+		iteration++
+		return iteration < iterations
+	})
+
+	generated := make([]linalg.Vector, evalCount)
+	for i := range generated {
+		generated[i] = fm.Generate()
+	}
+	coverage := mixture.Coverage(generated, evalCount/(modeCount*10))
+	if coverage.ModesCovered != modeCount {
+		t.Errorf("only covered %d/%d modes (counts %v)", coverage.ModesCovered,
+			modeCount, coverage.ModeCounts)
+	}
+	if coverage.HighQuality < 0.5 {
+		t.Errorf("high-quality fraction %f is too low", coverage.HighQuality)
+	}
+}
+
+// sampleMixture is like Mixture.SampleSet, but it draws
+// from r rather than the global source.
+func sampleMixture(m *Mixture, n int, r *rand.Rand) *MixtureSet {
+	res := &MixtureSet{Samples: make([]neuralnet.VectorSample, n)}
+	for i := range res.Samples {
+		mode := r.Intn(len(m.Means))
+		vec := make(linalg.Vector, len(m.Means[mode]))
+		for j, x := range m.Means[mode] {
+			vec[j] = x + r.NormFloat64()*m.Stddev
+		}
+		label := make(linalg.Vector, len(m.Means))
+		label[mode] = 1
+		res.Samples[i] = neuralnet.VectorSample{Input: vec, Output: label}
+	}
+	return res
+}
@@ -0,0 +1,180 @@
+package eval
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"runtime"
+	"sort"
+	"sync"
+
+	"github.com/unixpickle/gans"
+	"github.com/unixpickle/num-analysis/linalg"
+	"github.com/unixpickle/sgd"
+	"github.com/unixpickle/weakai/neuralnet"
+)
+
+// HistogramHeight is the height of the distance histogram
+// drawn by NeighborImage.
+const HistogramHeight = 60
+
+// Neighbor is a training sample which is close to a
+// generated sample.
+type Neighbor struct {
+	// Index is the index of the training sample.
+	Index int
+
+	// Distance is the Euclidean distance between the
+	// generated sample and the training sample.
+	Distance float64
+}
+
+// NearestNeighbors finds the k nearest training samples
+// for each generated sample.
+//
+// If features is non-nil, distances are measured between
+// feature vectors (e.g. the output of a discriminator's
+// feature layers) rather than between raw samples.
+//
+// The training samples must be neuralnet.VectorSample
+// instances.
+// Distances are computed in parallel across all CPUs.
+func NearestNeighbors(samples []linalg.Vector, train sgd.SampleSet, k int,
+	features func(linalg.Vector) linalg.Vector) [][]Neighbor {
+	if features == nil {
+		features = func(v linalg.Vector) linalg.Vector {
+			return v
+		}
+	}
+	trainVecs := make([]linalg.Vector, train.Len())
+	for i := range trainVecs {
+		trainVecs[i] = features(train.GetSample(i).(neuralnet.VectorSample).Input)
+	}
+	sampleVecs := make([]linalg.Vector, len(samples))
+	for i, s := range samples {
+		sampleVecs[i] = features(s)
+	}
+
+	res := make([][]Neighbor, len(samples))
+	indices := make(chan int, len(samples))
+	for i := range samples {
+		indices <- i
+	}
+	close(indices)
+
+	var wg sync.WaitGroup
+	for i := 0; i < runtime.GOMAXPROCS(0); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := range indices {
+				res[idx] = nearest(sampleVecs[idx], trainVecs, k)
+			}
+		}()
+	}
+	wg.Wait()
+	return res
+}
+
+// NeighborImage renders each generated sample in a row,
+// followed by its nearest neighbors from left to right.
+// Below the grid, it draws a histogram of the distances
+// between every sample and its closest neighbor.
+//
+// The toTensor function converts vectors into images.
+func NeighborImage(samples []linalg.Vector, train sgd.SampleSet,
+	neighbors [][]Neighbor, toTensor func(linalg.Vector) *neuralnet.Tensor3) image.Image {
+	var tensors []*neuralnet.Tensor3
+	for i, sample := range samples {
+		tensors = append(tensors, toTensor(sample))
+		for _, n := range neighbors[i] {
+			vec := train.GetSample(n.Index).(neuralnet.VectorSample).Input
+			tensors = append(tensors, toTensor(vec))
+		}
+	}
+	var cols int
+	if len(neighbors) > 0 {
+		cols = len(neighbors[0]) + 1
+	}
+	var idx int
+	grid := gans.GridSample(len(samples), cols, func() *neuralnet.Tensor3 {
+		idx++
+		return tensors[idx-1]
+	})
+
+	var closest []float64
+	for _, n := range neighbors {
+		if len(n) > 0 {
+			closest = append(closest, n[0].Distance)
+		}
+	}
+	gridBounds := grid.Bounds()
+	hist := DistanceHistogram(closest, gridBounds.Dx(), HistogramHeight)
+
+	res := image.NewRGBA(image.Rect(0, 0, gridBounds.Dx(),
+		gridBounds.Dy()+HistogramHeight))
+	draw.Draw(res, gridBounds, grid, gridBounds.Min, draw.Src)
+	draw.Draw(res, hist.Bounds().Add(image.Pt(0, gridBounds.Dy())), hist,
+		image.ZP, draw.Src)
+	return res
+}
+
+// DistanceHistogram draws a histogram of distances with
+// one bin per pixel column.
+// The horizontal axis spans from zero to the maximum
+// distance.
+func DistanceHistogram(distances []float64, width, height int) image.Image {
+	res := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(res, res.Bounds(), image.White, image.ZP, draw.Src)
+	if len(distances) == 0 || width == 0 {
+		return res
+	}
+
+	var maxDist float64
+	for _, d := range distances {
+		maxDist = math.Max(maxDist, d)
+	}
+	bins := make([]int, width)
+	for _, d := range distances {
+		bin := width - 1
+		if maxDist > 0 {
+			bin = int(d / maxDist * float64(width-1))
+		}
+		bins[bin]++
+	}
+	var maxCount int
+	for _, c := range bins {
+		if c > maxCount {
+			maxCount = c
+		}
+	}
+
+	barColor := color.RGBA{R: 0x30, G: 0x60, B: 0xc0, A: 0xff}
+	for x, c := range bins {
+		barHeight := c * height / maxCount
+		for y := height - barHeight; y < height; y++ {
+			res.SetRGBA(x, y, barColor)
+		}
+	}
+	return res
+}
+
+func nearest(vec linalg.Vector, train []linalg.Vector, k int) []Neighbor {
+	all := make([]Neighbor, len(train))
+	for i, t := range train {
+		var sum float64
+		for j, x := range t {
+			diff := x - vec[j]
+			sum += diff * diff
+		}
+		all[i] = Neighbor{Index: i, Distance: math.Sqrt(sum)}
+	}
+	sort.Slice(all, func(i, j int) bool {
+		return all[i].Distance < all[j].Distance
+	})
+	if k < len(all) {
+		all = all[:k]
+	}
+	return all
+}
@@ -0,0 +1,171 @@
+package eval
+
+import (
+	"errors"
+	"math"
+	"runtime"
+	"sync"
+
+	"github.com/unixpickle/num-analysis/linalg"
+	"github.com/unixpickle/sgd"
+	"github.com/unixpickle/weakai/neuralnet"
+)
+
+// Parzen is a Gaussian Parzen-window density estimate,
+// as used to evaluate the original GAN paper.
+type Parzen struct {
+	// Centers are the samples at which the Gaussian
+	// kernels are centered.
+	Centers []linalg.Vector
+
+	// Sigma is the standard deviation of every kernel.
+	Sigma float64
+}
+
+// NewParzen creates a Parzen estimator centered at n
+// samples drawn from a generator.
+// The Sigma field is not set.
+func NewParzen(gen func() linalg.Vector, n int) *Parzen {
+	res := &Parzen{Centers: make([]linalg.Vector, n)}
+	for i := range res.Centers {
+		res.Centers[i] = gen()
+	}
+	return res
+}
+
+// ParzenSigmas returns count bandwidths spaced evenly in
+// log space between min and max, inclusive.
+func ParzenSigmas(min, max float64, count int) []float64 {
+	if count == 1 {
+		return []float64{min}
+	}
+	res := make([]float64, count)
+	logMin, logMax := math.Log(min), math.Log(max)
+	for i := range res {
+		frac := float64(i) / float64(count-1)
+		res[i] = math.Exp(logMin + frac*(logMax-logMin))
+	}
+	return res
+}
+
+// FitSigma sets p.Sigma to the bandwidth which maximizes
+// the mean log-likelihood of the validation samples and
+// returns the chosen bandwidth.
+//
+// The samples in validation must be
+// neuralnet.VectorSample instances.
+// An error is returned if there are no sigmas, centers,
+// or validation samples to choose from, in which case
+// p.Sigma is left unchanged.
+func (p *Parzen) FitSigma(validation sgd.SampleSet, sigmas []float64) (float64, error) {
+	if len(sigmas) == 0 {
+		return 0, errors.New("no sigmas to choose from")
+	} else if len(p.Centers) == 0 {
+		return 0, errors.New("no Parzen centers")
+	} else if validation.Len() == 0 {
+		return 0, errors.New("no validation samples")
+	}
+	lls := p.meanLogLikelihoods(validation, sigmas)
+	best := 0
+	for i, ll := range lls {
+		if ll > lls[best] {
+			best = i
+		}
+	}
+	p.Sigma = sigmas[best]
+	return p.Sigma, nil
+}
+
+// LogLikelihood computes the log-likelihood of a vector.
+// If there are no centers, the density is zero everywhere
+// and the result is negative infinity.
+func (p *Parzen) LogLikelihood(x linalg.Vector) float64 {
+	return p.logLikelihoods(x, []float64{p.Sigma})[0]
+}
+
+// MeanLogLikelihood computes the mean log-likelihood of
+// the samples in s, which must be neuralnet.VectorSample
+// instances.
+// The computation is spread across all available CPUs.
+// As with LogLikelihood, the result is negative infinity
+// if there are no centers.
+func (p *Parzen) MeanLogLikelihood(s sgd.SampleSet) float64 {
+	return p.meanLogLikelihoods(s, []float64{p.Sigma})[0]
+}
+
+func (p *Parzen) meanLogLikelihoods(s sgd.SampleSet, sigmas []float64) []float64 {
+	indices := make(chan int, s.Len())
+	for i := 0; i < s.Len(); i++ {
+		indices <- i
+	}
+	close(indices)
+
+	numWorkers := runtime.GOMAXPROCS(0)
+	sums := make(chan []float64, numWorkers)
+	var wg sync.WaitGroup
+	for i := 0; i < numWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sum := make([]float64, len(sigmas))
+			for idx := range indices {
+				x := s.GetSample(idx).(neuralnet.VectorSample).Input
+				for j, ll := range p.logLikelihoods(x, sigmas) {
+					sum[j] += ll
+				}
+			}
+			sums <- sum
+		}()
+	}
+	wg.Wait()
+	close(sums)
+
+	res := make([]float64, len(sigmas))
+	for sum := range sums {
+		for i, x := range sum {
+			res[i] += x
+		}
+	}
+	for i := range res {
+		res[i] /= float64(s.Len())
+	}
+	return res
+}
+
+// logLikelihoods computes the log-likelihood of x for
+// every bandwidth in sigmas.
+func (p *Parzen) logLikelihoods(x linalg.Vector, sigmas []float64) []float64 {
+	res := make([]float64, len(sigmas))
+	if len(p.Centers) == 0 {
+		for i := range res {
+			res[i] = math.Inf(-1)
+		}
+		return res
+	}
+
+	sqDists := make([]float64, len(p.Centers))
+	for i, center := range p.Centers {
+		var sum float64
+		for j, c := range center {
+			diff := x[j] - c
+			sum += diff * diff
+		}
+		sqDists[i] = sum
+	}
+
+	dim := float64(len(x))
+	for i, sigma := range sigmas {
+		coeff := -1 / (2 * sigma * sigma)
+		maxExp := math.Inf(-1)
+		for _, d := range sqDists {
+			maxExp = math.Max(maxExp, coeff*d)
+		}
+		var expSum float64
+		for _, d := range sqDists {
+			expSum += math.Exp(coeff*d - maxExp)
+		}
+		res[i] = maxExp + math.Log(expSum) - math.Log(float64(len(sqDists))) -
+			dim/2*math.Log(2*math.Pi*sigma*sigma)
+	}
+	return res
+}
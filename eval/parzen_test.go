@@ -0,0 +1,36 @@
+package eval
+
+import (
+	"math"
+	"testing"
+
+	"github.com/unixpickle/num-analysis/linalg"
+	"github.com/unixpickle/sgd"
+	"github.com/unixpickle/weakai/neuralnet"
+)
+
+func TestParzenFitSigma(t *testing.T) {
+	p := &Parzen{Centers: []linalg.Vector{{0, 0}, {1, 0}}}
+	validation := sgd.SliceSampleSet{
+		neuralnet.VectorSample{Input: linalg.Vector{0.5, 0}},
+	}
+	sigma, err := p.FitSigma(validation, []float64{0.01, 0.5, 100})
+	if err != nil {
+		t.Fatal(err)
+	} else if sigma != 0.5 || p.Sigma != 0.5 {
+		t.Errorf("expected sigma 0.5 but got %f (field %f)", sigma, p.Sigma)
+	}
+	if _, err := p.FitSigma(validation, nil); err == nil {
+		t.Error("expected error for empty sigmas")
+	}
+	if _, err := (&Parzen{}).FitSigma(validation, []float64{1}); err == nil {
+		t.Error("expected error for empty centers")
+	}
+}
+
+func TestParzenNoCenters(t *testing.T) {
+	p := &Parzen{Sigma: 1}
+	if ll := p.LogLikelihood(linalg.Vector{1, 2}); !math.IsInf(ll, -1) {
+		t.Errorf("expected -Inf but got %f", ll)
+	}
+}
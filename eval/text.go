@@ -0,0 +1,298 @@
+package eval
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// TextMetrics computes automatic quality metrics for
+// generated text samples.
+type TextMetrics struct {
+	// Corpus is a list of real sentences whose character
+	// n-gram distribution is compared to the samples'.
+	Corpus []string
+
+	// References is a list of held-out real sentences used
+	// for computing BLEU.
+	References []string
+
+	// NGram is the character n-gram size used for the
+	// n-gram divergence and distinct-n metrics.
+	// Characters are UTF-8 runes, not bytes.
+	// If it is 0, 3 is used.
+	NGram int
+
+	// BLEUOrder is the maximum n-gram order for BLEU.
+	// If it is 0, 4 is used.
+	BLEUOrder int
+
+	// Tokenize splits sentences into tokens for BLEU.
+	// If it is nil, strings.Fields is used.
+	Tokenize func(string) []string
+
+	corpusCounts map[string]int
+}
+
+// TextReport stores the results of TextMetrics.
+type TextReport struct {
+	// NGramDivergence is the Jensen-Shannon divergence
+	// (in nats) between the character n-gram distributions
+	// of the samples and the corpus.
+	NGramDivergence float64
+
+	// BLEU is the corpus-level BLEU score of the samples
+	// against the references.
+	BLEU float64
+
+	// SelfBLEU is the mean BLEU score of every sample
+	// against all the other samples.
+	// High values indicate a lack of diversity.
+	SelfBLEU float64
+
+	// DistinctN is the fraction of the samples' character
+	// n-grams which are unique.
+	DistinctN float64
+
+	// Printable is the fraction of the samples' bytes in
+	// the printable ASCII range.
+	Printable float64
+}
+
+// String formats the report for logging.
+func (t *TextReport) String() string {
+	return fmt.Sprintf("ngram_js=%f bleu=%f self_bleu=%f distinct=%f printable=%f",
+		t.NGramDivergence, t.BLEU, t.SelfBLEU, t.DistinctN, t.Printable)
+}
+
+// Evaluate computes every metric on the samples.
+//
+// The corpus n-gram counts are cached after the first
+// call, so Corpus should not be modified afterwards.
+func (t *TextMetrics) Evaluate(samples []string) *TextReport {
+	if t.corpusCounts == nil {
+		t.corpusCounts = charNGrams(t.Corpus, t.ngram())
+	}
+	tokens := t.tokenizeAll(samples)
+	return &TextReport{
+		NGramDivergence: jsDivergence(charNGrams(samples, t.ngram()), t.corpusCounts),
+		BLEU:            BLEU(tokens, t.tokenizeAll(t.References), t.bleuOrder()),
+		SelfBLEU:        SelfBLEU(tokens, t.bleuOrder()),
+		DistinctN:       DistinctN(samples, t.ngram()),
+		Printable:       PrintableFraction(samples),
+	}
+}
+
+func (t *TextMetrics) ngram() int {
+	if t.NGram == 0 {
+		return 3
+	}
+	return t.NGram
+}
+
+func (t *TextMetrics) bleuOrder() int {
+	if t.BLEUOrder == 0 {
+		return 4
+	}
+	return t.BLEUOrder
+}
+
+func (t *TextMetrics) tokenizeAll(sentences []string) [][]string {
+	tokenize := t.Tokenize
+	if tokenize == nil {
+		tokenize = strings.Fields
+	}
+	res := make([][]string, len(sentences))
+	for i, s := range sentences {
+		res[i] = tokenize(s)
+	}
+	return res
+}
+
+// NGramDivergence computes the Jensen-Shannon divergence
+// between the character n-gram distributions of two sets
+// of sentences.
+func NGramDivergence(samples, corpus []string, n int) float64 {
+	return jsDivergence(charNGrams(samples, n), charNGrams(corpus, n))
+}
+
+// BLEU computes the corpus-level BLEU score of candidate
+// token sequences, using every reference for every
+// candidate.
+//
+// Precisions which would be zero are smoothed so that the
+// score degrades gracefully for short or poor samples.
+func BLEU(candidates, references [][]string, maxOrder int) float64 {
+	maxRefCounts := make([]map[string]int, maxOrder)
+	for n := range maxRefCounts {
+		maxRefCounts[n] = map[string]int{}
+		for _, ref := range references {
+			for gram, count := range tokenNGrams(ref, n+1) {
+				if count > maxRefCounts[n][gram] {
+					maxRefCounts[n][gram] = count
+				}
+			}
+		}
+	}
+	refLengths := make([]int, len(references))
+	for i, ref := range references {
+		refLengths[i] = len(ref)
+	}
+	sort.Ints(refLengths)
+
+	matches := make([]int, maxOrder)
+	totals := make([]int, maxOrder)
+	var candLength, refLength int
+	for _, cand := range candidates {
+		candLength += len(cand)
+		refLength += closestLength(refLengths, len(cand))
+		for n := range matches {
+			for gram, count := range tokenNGrams(cand, n+1) {
+				totals[n] += count
+				if refCount := maxRefCounts[n][gram]; refCount < count {
+					matches[n] += refCount
+				} else {
+					matches[n] += count
+				}
+			}
+		}
+	}
+	if candLength == 0 {
+		return 0
+	}
+
+	var logPrecision float64
+	for n := range matches {
+		if totals[n] == 0 {
+			return 0
+		}
+		numerator := float64(matches[n])
+		if numerator == 0 {
+			numerator = 0.1
+		}
+		logPrecision += math.Log(numerator/float64(totals[n])) / float64(maxOrder)
+	}
+	var logBrevity float64
+	if candLength < refLength {
+		logBrevity = 1 - float64(refLength)/float64(candLength)
+	}
+	return math.Exp(logPrecision + logBrevity)
+}
+
+// SelfBLEU computes the mean BLEU score of every sample
+// when all of the other samples are used as references.
+func SelfBLEU(samples [][]string, maxOrder int) float64 {
+	if len(samples) < 2 {
+		return 0
+	}
+	var sum float64
+	for i, sample := range samples {
+		others := make([][]string, 0, len(samples)-1)
+		others = append(others, samples[:i]...)
+		others = append(others, samples[i+1:]...)
+		sum += BLEU([][]string{sample}, others, maxOrder)
+	}
+	return sum / float64(len(samples))
+}
+
+// DistinctN computes the fraction of character n-grams
+// in the samples which are distinct.
+func DistinctN(samples []string, n int) float64 {
+	counts := charNGrams(samples, n)
+	var total int
+	for _, c := range counts {
+		total += c
+	}
+	if total == 0 {
+		return 0
+	}
+	return float64(len(counts)) / float64(total)
+}
+
+// PrintableFraction computes the fraction of bytes in the
+// samples which are printable ASCII characters.
+func PrintableFraction(samples []string) float64 {
+	var printable, total int
+	for _, s := range samples {
+		for i := 0; i < len(s); i++ {
+			if s[i] >= 0x20 && s[i] < 0x7f {
+				printable++
+			}
+		}
+		total += len(s)
+	}
+	if total == 0 {
+		return 0
+	}
+	return float64(printable) / float64(total)
+}
+
+// charNGrams counts the n-grams of runes in the sentences.
+// Invalid UTF-8 bytes are all counted as U+FFFD.
+func charNGrams(sentences []string, n int) map[string]int {
+	res := map[string]int{}
+	for _, s := range sentences {
+		runes := []rune(s)
+		for i := 0; i+n <= len(runes); i++ {
+			res[string(runes[i:i+n])]++
+		}
+	}
+	return res
+}
+
+func tokenNGrams(tokens []string, n int) map[string]int {
+	res := map[string]int{}
+	for i := 0; i+n <= len(tokens); i++ {
+		res[strings.Join(tokens[i:i+n], "\x00")]++
+	}
+	return res
+}
+
+func jsDivergence(counts1, counts2 map[string]int) float64 {
+	var total1, total2 float64
+	for _, c := range counts1 {
+		total1 += float64(c)
+	}
+	for _, c := range counts2 {
+		total2 += float64(c)
+	}
+	if total1 == 0 || total2 == 0 {
+		return math.Log(2)
+	}
+
+	keys := map[string]bool{}
+	for k := range counts1 {
+		keys[k] = true
+	}
+	for k := range counts2 {
+		keys[k] = true
+	}
+
+	var res float64
+	for k := range keys {
+		p := float64(counts1[k]) / total1
+		q := float64(counts2[k]) / total2
+		m := (p + q) / 2
+		if p > 0 {
+			res += p * math.Log(p/m) / 2
+		}
+		if q > 0 {
+			res += q * math.Log(q/m) / 2
+		}
+	}
+	return res
+}
+
+func closestLength(sorted []int, length int) int {
+	if len(sorted) == 0 {
+		return length
+	}
+	idx := sort.SearchInts(sorted, length)
+	if idx == len(sorted) {
+		return sorted[idx-1]
+	} else if idx == 0 || sorted[idx]-length < length-sorted[idx-1] {
+		return sorted[idx]
+	}
+	return sorted[idx-1]
+}
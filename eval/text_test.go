@@ -0,0 +1,13 @@
+package eval
+
+import "testing"
+
+func TestCharNGramsRunes(t *testing.T) {
+	counts := charNGrams([]string{"héé", "éé"}, 2)
+	if len(counts) != 2 || counts["hé"] != 1 || counts["éé"] != 2 {
+		t.Errorf("unexpected counts: %v", counts)
+	}
+	if d := DistinctN([]string{"ééé"}, 2); d != 0.5 {
+		t.Errorf("expected distinct-2 of 0.5 but got %f", d)
+	}
+}
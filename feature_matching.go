@@ -11,6 +11,10 @@ import (
 	"github.com/unixpickle/weakai/neuralnet"
 )
 
+// DefaultUnrollStepSize is the step size used for
+// unrolled discriminator steps when FM.UnrollStepSize is 0.
+const DefaultUnrollStepSize = 0.001
+
 func init() {
 	var f FM
 	serializer.RegisterTypedDeserializer(f.SerializerType(), DeserializeFM)
@@ -35,6 +39,68 @@ type FM struct {
 	// RandomSize is the size of the generator's random
 	// input vectors.
 	RandomSize int
+
+	// EMADecay, if non-zero, enables an exponential moving
+	// average of the generator's parameters which is kept
+	// in EMAGenerator and updated by UpdateEMA.
+	EMADecay float64
+
+	// EMAWarmup is the number of UpdateEMA calls during
+	// which EMAGenerator exactly tracks the generator's
+	// parameters instead of averaging them.
+	EMAWarmup int
+
+	// EMAGenerator is the averaged copy of Generator.
+	// It is created by the first UpdateEMA call if EMADecay
+	// is set.
+	EMAGenerator neuralnet.Network
+
+	// Noise, if non-nil, configures label smoothing,
+	// label flipping, and instance noise for the
+	// discriminator.
+	Noise *DiscNoise
+
+	// Replay, if non-nil, stores past generator outputs
+	// and mixes them into the discriminator's batches.
+	Replay *ReplayBuffer
+
+	// UnrollSteps, if non-zero, is the number of steps for
+	// which a scratch copy of the discriminator is trained
+	// before being used to compute the generator's gradient.
+	//
+	// This is a cheap approximation of unrolled GANs, not
+	// the procedure of Metz et al.: the copy is trained on
+	// the same real and generated batch for every unrolled
+	// step, and the generator's gradient is first-order
+	// only, i.e. it is computed through the features of the
+	// trained copy but not back through the unrolled
+	// discriminator updates themselves.
+	UnrollSteps int
+
+	// UnrollStepSize is the step size used for unrolled
+	// discriminator steps.
+	// A value of 0 is treated as DefaultUnrollStepSize.
+	UnrollStepSize float64
+
+	// UnrollTrans, if non-nil, creates a transformer (e.g.
+	// an *sgd.RMSProp) for the unrolled steps.
+	// A new transformer is needed for every Gradient call,
+	// since each scratch discriminator has new variables.
+	UnrollTrans func() sgd.Transformer
+
+	// FixedLatents are latent vectors which are kept with
+	// the model so that its progress can be visualized
+	// consistently, e.g. by a Timelapse.
+	FixedLatents []linalg.Vector
+
+	// Augment, if non-nil, randomly augments both the real
+	// and the generated samples before they are fed to the
+	// discriminator.
+	// Gradients are propagated through the augmentations
+	// of generated samples.
+	Augment *Augmentation
+
+	emaSteps int
 }
 
 // DeserializeFM deserializes an instance
@@ -44,7 +110,7 @@ func DeserializeFM(d []byte) (*FM, error) {
 	if err != nil {
 		return nil, err
 	}
-	if len(slice) != 4 {
+	if len(slice) != 4 && (len(slice) < 8 || len(slice) > 12) && len(slice) != 14 {
 		return nil, errors.New("invalid FM slice")
 	}
 	discrim, ok1 := slice[0].(neuralnet.Network)
@@ -54,12 +120,81 @@ func DeserializeFM(d []byte) (*FM, error) {
 	if !ok1 || !ok2 || !ok3 || !ok4 {
 		return nil, errors.New("invalid FM slice")
 	}
-	return &FM{
+	res := &FM{
 		Discriminator: discrim,
 		FeatureLayers: int(layers),
 		Generator:     gen,
 		RandomSize:    int(size),
-	}, nil
+	}
+	if len(slice) == 4 {
+		return res, nil
+	}
+	emaGen, ok1 := slice[4].(neuralnet.Network)
+	decay, ok2 := slice[5].(serializer.Float64)
+	warmup, ok3 := slice[6].(serializer.Int)
+	steps, ok4 := slice[7].(serializer.Int)
+	if !ok1 || !ok2 || !ok3 || !ok4 {
+		return nil, errors.New("invalid FM slice")
+	}
+	if len(emaGen) > 0 {
+		res.EMAGenerator = emaGen
+	}
+	res.EMADecay = float64(decay)
+	res.EMAWarmup = int(warmup)
+	res.emaSteps = int(steps)
+	if len(slice) == 8 {
+		return res, nil
+	}
+	noise, ok := slice[8].(*DiscNoise)
+	if !ok {
+		return nil, errors.New("invalid FM slice")
+	}
+	res.Noise = noise
+	if len(slice) == 9 {
+		return res, nil
+	}
+	replay, ok := slice[9].(*ReplayBuffer)
+	if !ok {
+		return nil, errors.New("invalid FM slice")
+	}
+	if replay.Capacity > 0 {
+		res.Replay = replay
+	}
+	if len(slice) == 10 {
+		return res, nil
+	}
+	latents, ok := slice[10].(serializer.Float64Slice)
+	if !ok {
+		return nil, errors.New("invalid FM slice")
+	}
+	if len(latents) > 0 && (res.RandomSize <= 0 || len(latents)%res.RandomSize != 0) {
+		return nil, errors.New("invalid FM fixed latents")
+	}
+	for i := 0; i < len(latents); i += res.RandomSize {
+		res.FixedLatents = append(res.FixedLatents,
+			linalg.Vector(latents[i:i+res.RandomSize]))
+	}
+	if len(slice) == 11 {
+		return res, nil
+	}
+	augment, ok := slice[11].(*Augmentation)
+	if !ok {
+		return nil, errors.New("invalid FM slice")
+	}
+	if augment.Width > 0 {
+		res.Augment = augment
+	}
+	if len(slice) == 12 {
+		return res, nil
+	}
+	unrollSteps, ok1 := slice[12].(serializer.Int)
+	unrollStepSize, ok2 := slice[13].(serializer.Float64)
+	if !ok1 || !ok2 || unrollSteps < 0 {
+		return nil, errors.New("invalid FM slice")
+	}
+	res.UnrollSteps = int(unrollSteps)
+	res.UnrollStepSize = float64(unrollStepSize)
+	return res, nil
 }
 
 // Gradient computes the gradient to train both the
@@ -74,32 +209,27 @@ func (f *FM) Gradient(samples sgd.SampleSet) autofunc.Gradient {
 		vecSamp := samples.GetSample(i).(neuralnet.VectorSample)
 		realBatch = append(realBatch, vecSamp.Input...)
 	}
-	featureNet := f.Discriminator[:f.FeatureLayers].BatchLearner()
-	discrimTail := f.Discriminator[f.FeatureLayers:].BatchLearner()
-
-	realFeatures := featureNet.Batch(&autofunc.Variable{Vector: realBatch}, n)
-	realOutput := discrimTail.Batch(realFeatures, n)
-	realMean := meanFeatures(realFeatures, n)
+	realBatch = f.Noise.noisyVec(f.Augment.batchVec(realBatch, n))
 
-	randomIn := make(linalg.Vector, n*f.RandomSize)
-	for i := range randomIn {
-		randomIn[i] = rand.NormFloat64()
+	randomIn := f.randomInput(n)
+	rawGenOut := f.Generator.BatchLearner().Batch(&autofunc.Variable{Vector: randomIn}, n)
+	genOut := f.Noise.noisyResult(f.Augment.Batch(rawGenOut, n))
+	discrimGenBatch := genOut.Output()
+	if f.Replay != nil {
+		replayBatch := f.replayBatch(rawGenOut.Output(), n)
+		discrimGenBatch = f.Noise.noisyVec(f.Augment.batchVec(replayBatch, n))
 	}
-	genOut := f.Generator.BatchLearner().Batch(&autofunc.Variable{Vector: randomIn}, n)
-	genMeanFeatures := meanFeatures(featureNet.Batch(genOut, n), n)
-	genCost := neuralnet.MeanSquaredCost{}.Cost(realMean.Output(), genMeanFeatures)
 
-	genGrad := autofunc.NewGradient(f.Generator.Parameters())
-	genCost.PropagateGradient(linalg.Vector{1}, genGrad)
+	featureDiscrim := f.Discriminator
+	if f.UnrollSteps > 0 {
+		featureDiscrim = f.unrolledDiscriminator(realBatch, discrimGenBatch, n)
+	}
+	genGrad := f.generatorGradient(featureDiscrim, realBatch, genOut, n)
 
-	genDiscrimOut := f.Discriminator.BatchLearner().Batch(genOut, samples.Len())
 	discrimGrad := autofunc.NewGradient(f.Discriminator.Parameters())
-	realDiscrimCost := neuralnet.SigmoidCECost{}.Cost(repeat(linalg.Vector{1}, n),
-		realOutput)
-	genDiscrimCost := neuralnet.SigmoidCECost{}.Cost(repeat(linalg.Vector{0}, n),
-		genDiscrimOut)
-	realDiscrimCost.PropagateGradient(linalg.Vector{0.1}, discrimGrad)
-	genDiscrimCost.PropagateGradient(linalg.Vector{0.1}, discrimGrad)
+	discrimCost := f.discriminatorCost(f.Discriminator, realBatch, discrimGenBatch, n)
+	discrimCost.PropagateGradient(linalg.Vector{0.1}, discrimGrad)
+	f.Noise.step()
 
 	resGrad := autofunc.Gradient{}
 	for _, subGrad := range []autofunc.Gradient{genGrad, discrimGrad} {
@@ -122,15 +252,35 @@ func (f *FM) SampleRealCost(samples sgd.SampleSet) float64 {
 // SampleGenCost measures the cross-entropy cost of the
 // discriminator on a generated input.
 func (f *FM) SampleGenCost() float64 {
-	genIn := make(linalg.Vector, f.RandomSize)
-	for i := range genIn {
-		genIn[i] = rand.NormFloat64()
-	}
+	genIn := f.randomInput(1)
 	genOut := f.Generator.Apply(&autofunc.Variable{Vector: genIn})
 	output := f.Discriminator.Apply(genOut)
 	return neuralnet.SigmoidCECost{}.Cost(linalg.Vector{0}, output).Output()[0]
 }
 
+// SampleGenerator returns the network which should be
+// used to produce samples.
+// This is EMAGenerator if it exists, or Generator
+// otherwise.
+func (f *FM) SampleGenerator() neuralnet.Network {
+	if f.EMAGenerator != nil {
+		return f.EMAGenerator
+	}
+	return f.Generator
+}
+
+// Generate produces a sample from a random latent vector
+// using SampleGenerator.
+func (f *FM) Generate() linalg.Vector {
+	return f.GenerateLatent(f.randomInput(1))
+}
+
+// GenerateLatent produces a sample from the given latent
+// vector using SampleGenerator.
+func (f *FM) GenerateLatent(latent linalg.Vector) linalg.Vector {
+	return f.SampleGenerator().Apply(&autofunc.Variable{Vector: latent}).Output()
+}
+
 // SerializerType returns the unique ID used to serialize
 // a FM instance with the serializer package.
 func (f *FM) SerializerType() string {
@@ -138,24 +288,165 @@ func (f *FM) SerializerType() string {
 }
 
 // Serialize serializes the instance as binary data.
+// UnrollTrans is not serialized, since it is a function.
 func (f *FM) Serialize() ([]byte, error) {
+	emaGen := f.EMAGenerator
+	if emaGen == nil {
+		emaGen = neuralnet.Network{}
+	}
+	noise := f.Noise
+	if noise == nil {
+		noise = &DiscNoise{}
+	}
+	replay := f.Replay
+	if replay == nil {
+		replay = &ReplayBuffer{}
+	}
+	augment := f.Augment
+	if augment == nil {
+		augment = &Augmentation{}
+	}
+	var latents serializer.Float64Slice
+	for _, latent := range f.FixedLatents {
+		latents = append(latents, latent...)
+	}
 	s := []serializer.Serializer{
 		f.Discriminator,
 		f.Generator,
 		serializer.Int(f.FeatureLayers),
 		serializer.Int(f.RandomSize),
+		emaGen,
+		serializer.Float64(f.EMADecay),
+		serializer.Int(f.EMAWarmup),
+		serializer.Int(f.emaSteps),
+		noise,
+		replay,
+		latents,
+		augment,
+		serializer.Int(f.UnrollSteps),
+		serializer.Float64(f.UnrollStepSize),
 	}
 	return serializer.SerializeSlice(s)
 }
 
-func repeat(vec linalg.Vector, n int) linalg.Vector {
+// UpdateEMA updates EMAGenerator with the generator's
+// current parameters, creating EMAGenerator if needed.
+// It does nothing if EMADecay is 0.
+//
+// Since Gradient cannot see its own step being applied,
+// the training loop should call UpdateEMA after every
+// step (e.g. at the start of an sgd.SGDMini status
+// function) and once more when training ends, so that
+// the last step is included before the model is saved.
+func (f *FM) UpdateEMA() {
+	if f.EMADecay == 0 {
+		return
+	}
+	if f.EMAGenerator == nil {
+		var err error
+		f.EMAGenerator, err = copyNetwork(f.Generator)
+		if err != nil {
+			panic("failed to copy generator: " + err.Error())
+		}
+	} else if f.emaSteps < f.EMAWarmup {
+		updateEMA(f.EMAGenerator, f.Generator, 0)
+	} else {
+		updateEMA(f.EMAGenerator, f.Generator, f.EMADecay)
+	}
+	f.emaSteps++
+}
+
+// replayBatch mixes a batch of generated samples with
+// samples from the replay buffer.
+func (f *FM) replayBatch(genBatch linalg.Vector, n int) linalg.Vector {
+	sampleSize := len(genBatch) / n
+	entries := make([][]linalg.Vector, n)
+	for i := range entries {
+		sample := append(linalg.Vector{}, genBatch[i*sampleSize:(i+1)*sampleSize]...)
+		entries[i] = []linalg.Vector{sample}
+	}
 	var res linalg.Vector
-	for i := 0; i < n; i++ {
-		res = append(res, vec...)
+	for _, entry := range f.Replay.Mix(entries) {
+		res = append(res, entry[0]...)
 	}
 	return res
 }
 
+// fixedLatents returns the first n fixed latent vectors,
+// generating more of them if necessary.
+func (f *FM) fixedLatents(n int) []linalg.Vector {
+	for len(f.FixedLatents) < n {
+		f.FixedLatents = append(f.FixedLatents, f.randomInput(1))
+	}
+	return f.FixedLatents[:n]
+}
+
+func (f *FM) randomInput(n int) linalg.Vector {
+	res := make(linalg.Vector, n*f.RandomSize)
+	for i := range res {
+		res[i] = rand.NormFloat64()
+	}
+	return res
+}
+
+// generatorGradient computes the feature matching
+// gradient for the generator using the given
+// discriminator's features.
+func (f *FM) generatorGradient(discrim neuralnet.Network, realBatch linalg.Vector,
+	genOut autofunc.Result, n int) autofunc.Gradient {
+	featureNet := discrim[:f.FeatureLayers].BatchLearner()
+	realFeatures := featureNet.Batch(&autofunc.Variable{Vector: realBatch}, n)
+	realMean := meanFeatures(realFeatures, n)
+	genMeanFeatures := meanFeatures(featureNet.Batch(genOut, n), n)
+	genCost := neuralnet.MeanSquaredCost{}.Cost(realMean.Output(), genMeanFeatures)
+
+	genGrad := autofunc.NewGradient(f.Generator.Parameters())
+	genCost.PropagateGradient(linalg.Vector{1}, genGrad)
+	return genGrad
+}
+
+// discriminatorCost computes the total cross-entropy
+// cost of a discriminator on a batch of real samples and
+// a batch of generated samples.
+func (f *FM) discriminatorCost(discrim neuralnet.Network, realBatch,
+	genBatch linalg.Vector, n int) autofunc.Result {
+	learner := discrim.BatchLearner()
+	realOutput := learner.Batch(&autofunc.Variable{Vector: realBatch}, n)
+	genOutput := learner.Batch(&autofunc.Variable{Vector: genBatch}, n)
+	realCost := neuralnet.SigmoidCECost{}.Cost(f.Noise.targets(n, true), realOutput)
+	genCost := neuralnet.SigmoidCECost{}.Cost(f.Noise.targets(n, false), genOutput)
+	return autofunc.Add(realCost, genCost)
+}
+
+// unrolledDiscriminator trains a copy of the
+// discriminator for UnrollSteps steps, reusing the same
+// batches for every step.
+func (f *FM) unrolledDiscriminator(realBatch, genBatch linalg.Vector,
+	n int) neuralnet.Network {
+	discrim, err := copyNetwork(f.Discriminator)
+	if err != nil {
+		panic("failed to copy discriminator: " + err.Error())
+	}
+	var trans sgd.Transformer
+	if f.UnrollTrans != nil {
+		trans = f.UnrollTrans()
+	}
+	stepSize := f.UnrollStepSize
+	if stepSize == 0 {
+		stepSize = DefaultUnrollStepSize
+	}
+	for i := 0; i < f.UnrollSteps; i++ {
+		grad := autofunc.NewGradient(discrim.Parameters())
+		cost := f.discriminatorCost(discrim, realBatch, genBatch, n)
+		cost.PropagateGradient(linalg.Vector{0.1}, grad)
+		if trans != nil {
+			grad = trans.Transform(grad)
+		}
+		grad.AddToVars(-stepSize)
+	}
+	return discrim
+}
+
 func meanFeatures(features autofunc.Result, n int) autofunc.Result {
 	return autofunc.Pool(features, func(features autofunc.Result) autofunc.Result {
 		featureLen := len(features.Output()) / n
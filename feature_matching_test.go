@@ -0,0 +1,125 @@
+package gans
+
+import (
+	"math"
+	"testing"
+
+	"github.com/unixpickle/autofunc"
+	"github.com/unixpickle/num-analysis/linalg"
+	"github.com/unixpickle/weakai/neuralnet"
+)
+
+func TestFMUpdateEMA(t *testing.T) {
+	f := &FM{
+		Generator: neuralnet.Network{neuralnet.NewDenseLayer(2, 3)},
+		EMADecay:  0.5,
+		EMAWarmup: 2,
+	}
+
+	// The first update copies the generator and the next
+	// one is still within the warmup, so the average should
+	// exactly track the generator.
+	setParams(f.Generator, 1)
+	f.UpdateEMA()
+	checkParams(t, "copy", f.EMAGenerator, 1)
+	setParams(f.Generator, 3)
+	f.UpdateEMA()
+	checkParams(t, "warmup", f.EMAGenerator, 3)
+
+	// After the warmup, the decay applies.
+	setParams(f.Generator, 5)
+	f.UpdateEMA()
+	checkParams(t, "decay", f.EMAGenerator, 4)
+	checkParams(t, "generator", f.Generator, 5)
+}
+
+func TestFMUpdateEMADisabled(t *testing.T) {
+	f := &FM{Generator: neuralnet.Network{neuralnet.NewDenseLayer(2, 3)}}
+	f.UpdateEMA()
+	if f.EMAGenerator != nil {
+		t.Error("EMA generator should not be created without a decay")
+	}
+}
+
+func TestFMUnrolledGradient(t *testing.T) {
+	f := testFM()
+	f.UnrollSteps = 3
+	f.UnrollStepSize = 0.5
+
+	const n = 4
+	realBatch := randomVec(n * 2)
+	genOut := f.Generator.BatchLearner().Batch(&autofunc.Variable{
+		Vector: f.randomInput(n),
+	}, n)
+	discParams := append(linalg.Vector{}, f.Discriminator.Parameters()[0].Vector...)
+
+	plainGrad := f.generatorGradient(f.Discriminator, realBatch, genOut, n)
+	unrolled := f.unrolledDiscriminator(realBatch, genOut.Output(), n)
+	unrolledGrad := f.generatorGradient(unrolled, realBatch, genOut, n)
+
+	if !vecsEqual(discParams, f.Discriminator.Parameters()[0].Vector) {
+		t.Error("unrolling modified the discriminator")
+	}
+	var differs bool
+	for _, param := range f.Generator.Parameters() {
+		if !vecsEqual(plainGrad[param], unrolledGrad[param]) {
+			differs = true
+		}
+	}
+	if !differs {
+		t.Error("unrolled gradient is the same as the plain gradient")
+	}
+}
+
+func TestFMSerializeUnroll(t *testing.T) {
+	f := testFM()
+	f.UnrollSteps = 5
+	f.UnrollStepSize = 0.01
+	data, err := f.Serialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := DeserializeFM(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.UnrollSteps != f.UnrollSteps || loaded.UnrollStepSize != f.UnrollStepSize {
+		t.Errorf("expected unroll options %d, %f but got %d, %f", f.UnrollSteps,
+			f.UnrollStepSize, loaded.UnrollSteps, loaded.UnrollStepSize)
+	}
+}
+
+func testFM() *FM {
+	discrim := neuralnet.Network{
+		neuralnet.NewDenseLayer(2, 8),
+		neuralnet.HyperbolicTangent{},
+		neuralnet.NewDenseLayer(8, 1),
+	}
+	return &FM{
+		Discriminator: discrim,
+		FeatureLayers: 2,
+		Generator: neuralnet.Network{
+			neuralnet.NewDenseLayer(3, 2),
+		},
+		RandomSize: 3,
+	}
+}
+
+func setParams(n neuralnet.Network, x float64) {
+	for _, param := range n.Parameters() {
+		for i := range param.Vector {
+			param.Vector[i] = x
+		}
+	}
+}
+
+func checkParams(t *testing.T, name string, n neuralnet.Network, x float64) {
+	for _, param := range n.Parameters() {
+		for _, y := range param.Vector {
+			if math.Abs(x-y) > 1e-8 {
+				t.Errorf("%s: expected parameter %f but got %f", name, x, y)
+				return
+			}
+		}
+	}
+}
@@ -3,61 +3,256 @@ package gans
 import (
 	"image"
 	"image/color"
+	"math"
 
 	"github.com/unixpickle/weakai/neuralnet"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
 )
 
 const GridSpacing = 1
 
 var GridSpaceColor = color.RGBA{R: 0x80, G: 0x80, B: 0x80, A: 0xff}
 
+// A ValueRange determines how tensor values are mapped
+// to color intensities.
+type ValueRange int
+
+const (
+	// UnitRange maps [0, 1] to the full intensity range.
+	UnitRange ValueRange = iota
+
+	// SignedRange maps [-1, 1] to the full intensity
+	// range, as suits generators with tanh outputs.
+	SignedRange
+
+	// AutoRange maps the minimum and maximum values across
+	// all of the tiles to the full intensity range.
+	AutoRange
+)
+
+// GridOptions configures GridSampleOptions.
+//
+// Unlike the other fields, Spacing has no default:
+// a zero GridOptions draws tiles right next to each
+// other, whereas DefaultGridOptions separates them.
+type GridOptions struct {
+	// Spacing is the number of pixels between tiles.
+	// A value of 0 means there is no spacing.
+	Spacing int
+
+	// Background is the color between tiles and around
+	// tiles which are smaller than their cells.
+	// If it is nil, GridSpaceColor is used.
+	Background color.Color
+
+	// Range determines how values map to intensities.
+	Range ValueRange
+
+	// Overflow, if non-nil, is used to draw values which
+	// fall outside of Range.
+	// If it is nil, such values are clamped.
+	Overflow color.Color
+
+	// Scale is the number of pixels along each side of
+	// the square used to draw each tensor entry.
+	// A value of 0 is treated as 1.
+	Scale int
+
+	// Captions, if non-nil, contains a caption to draw
+	// beneath each tile, in row-major order.
+	//
+	// Captions are drawn with a fixed 7x13 bitmap font,
+	// since the standard library cannot render text.
+	// The font only covers printable ASCII, so other
+	// characters are drawn as placeholder glyphs.
+	Captions []string
+
+	// CaptionColor is the color of caption text.
+	// If it is nil, black is used.
+	CaptionColor color.Color
+}
+
+// DefaultGridOptions returns the options used by
+// GridSample.
+func DefaultGridOptions() *GridOptions {
+	return &GridOptions{
+		Spacing:    GridSpacing,
+		Background: GridSpaceColor,
+		Range:      UnitRange,
+	}
+}
+
 // GridSample samples images from a generator and arranges
 // them in a grid on an image.
-// Tensor images may either have a depth of 1 (grayscale)
-// or 3 (RGB).
+// Tensor images may either have a depth of 1 (grayscale),
+// 3 (RGB), or 4 (RGBA).
+// Values should be in the range [0, 1].
 func GridSample(rows, cols int, gen func() *neuralnet.Tensor3) image.Image {
-	if rows == 0 && cols == 0 {
-		return image.NewRGBA(image.Rect(0, 0, GridSpacing, GridSpacing))
+	return GridSampleOptions(rows, cols, nil, gen)
+}
+
+// GridSampleOptions is like GridSample, but with custom
+// options.
+// If opts is nil, DefaultGridOptions() is used.
+//
+// Tensors need not be the same size; each tile is
+// centered in a cell large enough for every tensor.
+// Tensors whose depth is not 1, 3, or 4 are rendered in
+// grayscale using their first channel.
+func GridSampleOptions(rows, cols int, opts *GridOptions,
+	gen func() *neuralnet.Tensor3) image.Image {
+	if opts == nil {
+		opts = DefaultGridOptions()
+	}
+	scale := opts.Scale
+	if scale == 0 {
+		scale = 1
+	}
+	background := opts.Background
+	if background == nil {
+		background = GridSpaceColor
 	}
 
 	tensors := make([]*neuralnet.Tensor3, rows*cols)
 	for i := range tensors {
 		tensors[i] = gen()
 	}
+	minVal, maxVal := opts.valueRange(tensors)
+
+	var cellWidth, cellHeight, captionHeight int
+	for i, tensor := range tensors {
+		cellWidth = maxInt(cellWidth, tensor.Width*scale)
+		cellHeight = maxInt(cellHeight, tensor.Height*scale)
+		if i < len(opts.Captions) {
+			cellWidth = maxInt(cellWidth, textWidth(opts.Captions[i]))
+			captionHeight = basicfont.Face7x13.Height
+		}
+	}
+	cellHeight += captionHeight
 
-	newWidth := tensors[0].Width*cols + (cols+1)*GridSpacing
-	newHeight := tensors[0].Height*rows + (rows+1)*GridSpacing
+	spacing := opts.Spacing
+	newWidth := cellWidth*cols + (cols+1)*spacing
+	newHeight := cellHeight*rows + (rows+1)*spacing
 	img := image.NewRGBA(image.Rect(0, 0, newWidth, newHeight))
 	for y := 0; y < newHeight; y++ {
 		for x := 0; x < newWidth; x++ {
-			img.Set(x, y, GridSpaceColor)
+			img.Set(x, y, background)
 		}
 	}
 
 	var idx int
 	for y := 0; y < rows; y++ {
-		tileY := GridSpacing + y*(tensors[0].Height+GridSpacing)
+		cellY := spacing + y*(cellHeight+spacing)
 		for x := 0; x < cols; x++ {
-			tileX := GridSpacing + x*(tensors[0].Width+GridSpacing)
+			cellX := spacing + x*(cellWidth+spacing)
 			tensor := tensors[idx]
-			idx++
+			tileX := cellX + (cellWidth-tensor.Width*scale)/2
+			tileY := cellY + (cellHeight-captionHeight-tensor.Height*scale)/2
 			for j := 0; j < tensor.Height; j++ {
 				for k := 0; k < tensor.Width; k++ {
-					if tensor.Depth == 3 {
-						img.SetRGBA(tileX+k, tileY+j, color.RGBA{
-							R: uint8(tensor.Get(k, j, 0)*0xff + 0.5),
-							G: uint8(tensor.Get(k, j, 1)*0xff + 0.5),
-							B: uint8(tensor.Get(k, j, 2)*0xff + 0.5),
-							A: 0xff,
-						})
-					} else {
-						img.Set(tileX+k, tileY+j, color.Gray{
-							Y: uint8(tensor.Get(k, j, 0)*0xff + 0.5),
-						})
+					c := opts.pixelColor(tensor, k, j, minVal, maxVal)
+					for dy := 0; dy < scale; dy++ {
+						for dx := 0; dx < scale; dx++ {
+							img.Set(tileX+k*scale+dx, tileY+j*scale+dy, c)
+						}
 					}
 				}
 			}
+			if idx < len(opts.Captions) {
+				caption := opts.Captions[idx]
+				captionColor := opts.CaptionColor
+				if captionColor == nil {
+					captionColor = color.Black
+				}
+				drawText(img, caption, captionColor,
+					cellX+(cellWidth-textWidth(caption))/2,
+					cellY+cellHeight-captionHeight)
+			}
+			idx++
 		}
 	}
 	return img
 }
+
+func (g *GridOptions) valueRange(tensors []*neuralnet.Tensor3) (min, max float64) {
+	switch g.Range {
+	case SignedRange:
+		return -1, 1
+	case AutoRange:
+		min, max = math.Inf(1), math.Inf(-1)
+		for _, t := range tensors {
+			for _, x := range t.Data {
+				min = math.Min(min, x)
+				max = math.Max(max, x)
+			}
+		}
+		if math.IsInf(min, 1) {
+			return 0, 1
+		}
+		return min, max
+	default:
+		return 0, 1
+	}
+}
+
+func (g *GridOptions) pixelColor(t *neuralnet.Tensor3, x, y int, min,
+	max float64) color.Color {
+	var overflow bool
+	intensity := func(z int) uint8 {
+		val := t.Get(x, y, z)
+		if max > min {
+			val = (val - min) / (max - min)
+		} else {
+			val = 0.5
+		}
+		if val < 0 || val > 1 {
+			overflow = true
+			val = math.Max(0, math.Min(1, val))
+		}
+		return uint8(val*0xff + 0.5)
+	}
+
+	var res color.Color
+	switch t.Depth {
+	case 3:
+		res = color.RGBA{R: intensity(0), G: intensity(1), B: intensity(2), A: 0xff}
+	case 4:
+		res = color.NRGBA{R: intensity(0), G: intensity(1), B: intensity(2),
+			A: intensity(3)}
+	default:
+		res = color.Gray{Y: intensity(0)}
+	}
+	if overflow && g.Overflow != nil {
+		return g.Overflow
+	}
+	return res
+}
+
+// drawText draws a line of text whose top-left corner is
+// at the given coordinates.
+func drawText(img *image.RGBA, text string, c color.Color, x, y int) {
+	face := basicfont.Face7x13
+	drawer := &font.Drawer{
+		Dst:  img,
+		Src:  image.NewUniform(c),
+		Face: face,
+		Dot:  fixed.P(x, y+face.Ascent),
+	}
+	drawer.DrawString(text)
+}
+
+// textWidth measures the width of a line of text drawn
+// by drawText.
+func textWidth(text string) int {
+	drawer := &font.Drawer{Face: basicfont.Face7x13}
+	return drawer.MeasureString(text).Ceil()
+}
+
+func maxInt(x, y int) int {
+	if x > y {
+		return x
+	}
+	return y
+}
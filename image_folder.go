@@ -0,0 +1,229 @@
+package gans
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/unixpickle/sgd"
+	"github.com/unixpickle/weakai/neuralnet"
+)
+
+// ImageFolder is an sgd.SampleSet of images which are
+// loaded from disk as they are needed.
+//
+// Each sample is a neuralnet.VectorSample whose input is
+// the data of a neuralnet.Tensor3.
+// Images are scaled and center-cropped to the target
+// size.
+type ImageFolder struct {
+	// Paths contains the path of every image.
+	Paths []string
+
+	// Width and Height specify the target image size.
+	Width  int
+	Height int
+
+	// Gray specifies whether images should be converted
+	// to grayscale (depth 1) rather than RGB (depth 3).
+	Gray bool
+
+	// Range specifies how intensities are mapped to
+	// values, and must be UnitRange or SignedRange.
+	Range ValueRange
+}
+
+// LoadImageFolder creates an ImageFolder for the PNG,
+// JPEG, and GIF files in a directory.
+// The images themselves are not loaded, but their headers
+// are checked so that undecodable files are reported up
+// front rather than in the middle of training.
+func LoadImageFolder(dir string, width, height int, gray bool) (*ImageFolder, error) {
+	if width <= 0 || height <= 0 {
+		return nil, errors.New("image size must be positive")
+	}
+	listing, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	res := &ImageFolder{Width: width, Height: height, Gray: gray}
+	for _, info := range listing {
+		if info.IsDir() {
+			continue
+		}
+		switch strings.ToLower(filepath.Ext(info.Name())) {
+		case ".png", ".jpg", ".jpeg", ".gif":
+			path := filepath.Join(dir, info.Name())
+			if err := checkImageFile(path); err != nil {
+				return nil, err
+			}
+			res.Paths = append(res.Paths, path)
+		}
+	}
+	sort.Strings(res.Paths)
+	return res, nil
+}
+
+// Depth returns the depth of the image tensors.
+func (i *ImageFolder) Depth() int {
+	if i.Gray {
+		return 1
+	}
+	return 3
+}
+
+// Len returns the number of images.
+func (i *ImageFolder) Len() int {
+	return len(i.Paths)
+}
+
+// Swap swaps two images.
+func (i *ImageFolder) Swap(j, k int) {
+	i.Paths[j], i.Paths[k] = i.Paths[k], i.Paths[j]
+}
+
+// GetSample loads an image as a neuralnet.VectorSample.
+// It panics if the image cannot be loaded, which can only
+// happen for files whose headers were valid if the files
+// are changed or truncated after LoadImageFolder.
+func (i *ImageFolder) GetSample(idx int) interface{} {
+	tensor, err := i.Tensor(idx)
+	if err != nil {
+		panic(err)
+	}
+	return neuralnet.VectorSample{Input: tensor.Data}
+}
+
+// Copy creates a copy of the set.
+func (i *ImageFolder) Copy() sgd.SampleSet {
+	res := *i
+	res.Paths = append([]string{}, i.Paths...)
+	return &res
+}
+
+// Subset returns a subset of the set.
+func (i *ImageFolder) Subset(start, end int) sgd.SampleSet {
+	res := *i
+	res.Paths = i.Paths[start:end]
+	return &res
+}
+
+// Tensor loads an image as a tensor.
+func (i *ImageFolder) Tensor(idx int) (*neuralnet.Tensor3, error) {
+	if i.Range != UnitRange && i.Range != SignedRange {
+		return nil, errors.New("image folder range must be UnitRange or SignedRange")
+	}
+	f, err := os.Open(i.Paths[idx])
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("decode %s: %s", i.Paths[idx], err)
+	}
+	return ImageTensor(img, i.Width, i.Height, i.Gray, i.Range), nil
+}
+
+// ImageTensor scales and center-crops an image to the
+// given size and converts it to a tensor.
+// Scaling uses bilinear interpolation.
+// The value range must be UnitRange or SignedRange.
+// The width and height must be positive.
+func ImageTensor(img image.Image, width, height int, gray bool,
+	valRange ValueRange) *neuralnet.Tensor3 {
+	if width <= 0 || height <= 0 {
+		panic("image size must be positive")
+	} else if valRange != UnitRange && valRange != SignedRange {
+		panic("image value range must be UnitRange or SignedRange")
+	}
+	bounds := img.Bounds()
+	crop := bounds
+	if bounds.Dx()*height > bounds.Dy()*width {
+		cropWidth := maxInt(1, bounds.Dy()*width/height)
+		crop.Min.X += (bounds.Dx() - cropWidth) / 2
+		crop.Max.X = crop.Min.X + cropWidth
+	} else {
+		cropHeight := maxInt(1, bounds.Dx()*height/width)
+		crop.Min.Y += (bounds.Dy() - cropHeight) / 2
+		crop.Max.Y = crop.Min.Y + cropHeight
+	}
+	scaleX := float64(crop.Dx()) / float64(width)
+	scaleY := float64(crop.Dy()) / float64(height)
+
+	depth := 3
+	if gray {
+		depth = 1
+	}
+	res := neuralnet.NewTensor3(width, height, depth)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			srcX := float64(crop.Min.X) + (float64(x)+0.5)*scaleX - 0.5
+			srcY := float64(crop.Min.Y) + (float64(y)+0.5)*scaleY - 0.5
+			channels := bilinearRGB(img, crop, srcX, srcY)
+			if gray {
+				channels = []float64{0.299*channels[0] + 0.587*channels[1] +
+					0.114*channels[2]}
+			}
+			for z, val := range channels {
+				if valRange == SignedRange {
+					val = val*2 - 1
+				}
+				res.Set(x, y, z, val)
+			}
+		}
+	}
+	return res
+}
+
+// bilinearRGB interpolates the color of an image at a
+// point, clamping to the pixels within bounds.
+// The channels are scaled to the range [0, 1].
+func bilinearRGB(img image.Image, bounds image.Rectangle, x, y float64) []float64 {
+	x = math.Max(float64(bounds.Min.X), math.Min(float64(bounds.Max.X-1), x))
+	y = math.Max(float64(bounds.Min.Y), math.Min(float64(bounds.Max.Y-1), y))
+	x0, y0 := int(x), int(y)
+	x1, y1 := minInt(x0+1, bounds.Max.X-1), minInt(y0+1, bounds.Max.Y-1)
+	fracX, fracY := x-float64(x0), y-float64(y0)
+
+	res := make([]float64, 3)
+	for _, p := range []struct {
+		x, y   int
+		weight float64
+	}{
+		{x0, y0, (1 - fracX) * (1 - fracY)},
+		{x1, y0, fracX * (1 - fracY)},
+		{x0, y1, (1 - fracX) * fracY},
+		{x1, y1, fracX * fracY},
+	} {
+		r, g, b, _ := img.At(p.x, p.y).RGBA()
+		res[0] += p.weight * float64(r) / 0xffff
+		res[1] += p.weight * float64(g) / 0xffff
+		res[2] += p.weight * float64(b) / 0xffff
+	}
+	return res
+}
+
+func checkImageFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	config, _, err := image.DecodeConfig(f)
+	if err != nil {
+		return fmt.Errorf("decode %s: %s", path, err)
+	} else if config.Width == 0 || config.Height == 0 {
+		return fmt.Errorf("decode %s: empty image", path)
+	}
+	return nil
+}
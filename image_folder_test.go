@@ -0,0 +1,71 @@
+package gans
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestImageTensorScale(t *testing.T) {
+	img := image.NewGray(image.Rect(0, 0, 4, 2))
+	for y := 0; y < 2; y++ {
+		for x := 0; x < 4; x++ {
+			img.SetGray(x, y, color.Gray{Y: uint8(x * 0x55)})
+		}
+	}
+	tensor := ImageTensor(img, 2, 1, true, UnitRange)
+	expected := []float64{0.5 * 0x55 / 0xff, 2.5 * 0x55 / 0xff}
+	for i, x := range expected {
+		if math.Abs(tensor.Data[i]-x) > 1e-3 {
+			t.Errorf("pixel %d: expected %f but got %f", i, x, tensor.Data[i])
+		}
+	}
+
+	tensor = ImageTensor(img, 8, 4, false, SignedRange)
+	if tensor.Data[0] != -1 || math.Abs(tensor.Data[len(tensor.Data)-1]-1) > 1e-3 {
+		t.Errorf("unexpected corners: %f, %f", tensor.Data[0],
+			tensor.Data[len(tensor.Data)-1])
+	}
+}
+
+func TestLoadImageFolder(t *testing.T) {
+	dir, err := ioutil.TempDir("", "image_folder")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	f, err := os.Create(filepath.Join(dir, "good.png"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = png.Encode(f, image.NewGray(image.Rect(0, 0, 3, 3)))
+	f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	folder, err := LoadImageFolder(dir, 2, 2, true)
+	if err != nil {
+		t.Fatal(err)
+	} else if folder.Len() != 1 {
+		t.Fatalf("expected 1 image but got %d", folder.Len())
+	}
+
+	folder.Range = AutoRange
+	if _, err := folder.Tensor(0); err == nil {
+		t.Error("expected error for AutoRange")
+	}
+
+	err = ioutil.WriteFile(filepath.Join(dir, "bad.png"), []byte("not a png"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadImageFolder(dir, 2, 2, true); err == nil {
+		t.Error("expected error for corrupt image")
+	}
+}
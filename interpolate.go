@@ -0,0 +1,113 @@
+package gans
+
+import (
+	"image"
+	"image/color/palette"
+	"image/draw"
+	"image/gif"
+	"math"
+
+	"github.com/unixpickle/num-analysis/linalg"
+	"github.com/unixpickle/weakai/neuralnet"
+)
+
+// LerpLatents linearly interpolates between consecutive
+// latent vectors.
+// Each segment is divided into steps intervals, and the
+// result includes every endpoint.
+func LerpLatents(latents []linalg.Vector, steps int) []linalg.Vector {
+	return interpolateLatents(latents, steps, lerp)
+}
+
+// SlerpLatents spherically interpolates between
+// consecutive latent vectors, which keeps intermediate
+// vectors at a norm typical of Gaussian latents.
+// Each segment is divided into steps intervals, and the
+// result includes every endpoint.
+func SlerpLatents(latents []linalg.Vector, steps int) []linalg.Vector {
+	return interpolateLatents(latents, steps, slerp)
+}
+
+// InterpolationStrip renders a generator's output for
+// every latent vector in a single row.
+func InterpolationStrip(latents []linalg.Vector,
+	gen func(linalg.Vector) *neuralnet.Tensor3) image.Image {
+	var idx int
+	return GridSample(1, len(latents), func() *neuralnet.Tensor3 {
+		idx++
+		return gen(latents[idx-1])
+	})
+}
+
+// InterpolationGIF renders a generator's output for
+// every latent vector as a frame of an animation.
+// The delay between frames is measured in 100ths of a
+// second.
+func InterpolationGIF(latents []linalg.Vector, delay int,
+	gen func(linalg.Vector) *neuralnet.Tensor3) *gif.GIF {
+	frames := make([]image.Image, len(latents))
+	for i, latent := range latents {
+		tensor := gen(latent)
+		frames[i] = GridSample(1, 1, func() *neuralnet.Tensor3 {
+			return tensor
+		})
+	}
+	return AnimateGIF(frames, delay)
+}
+
+// AnimateGIF creates an animated GIF from a list of
+// frames, which are dithered to a standard palette.
+// The delay between frames is measured in 100ths of a
+// second.
+func AnimateGIF(frames []image.Image, delay int) *gif.GIF {
+	res := &gif.GIF{}
+	for _, frame := range frames {
+		paletted := image.NewPaletted(frame.Bounds(), palette.Plan9)
+		draw.FloydSteinberg.Draw(paletted, frame.Bounds(), frame, frame.Bounds().Min)
+		res.Image = append(res.Image, paletted)
+		res.Delay = append(res.Delay, delay)
+	}
+	return res
+}
+
+func interpolateLatents(latents []linalg.Vector, steps int,
+	f func(v1, v2 linalg.Vector, t float64) linalg.Vector) []linalg.Vector {
+	if len(latents) < 2 || steps < 1 {
+		return latents
+	}
+	var res []linalg.Vector
+	for i := 1; i < len(latents); i++ {
+		for j := 0; j < steps; j++ {
+			res = append(res, f(latents[i-1], latents[i], float64(j)/float64(steps)))
+		}
+	}
+	return append(res, latents[len(latents)-1])
+}
+
+func lerp(v1, v2 linalg.Vector, t float64) linalg.Vector {
+	res := make(linalg.Vector, len(v1))
+	for i, x := range v1 {
+		res[i] = x*(1-t) + v2[i]*t
+	}
+	return res
+}
+
+func slerp(v1, v2 linalg.Vector, t float64) linalg.Vector {
+	norm1 := math.Sqrt(v1.Dot(v1))
+	norm2 := math.Sqrt(v2.Dot(v2))
+	if norm1 == 0 || norm2 == 0 {
+		return lerp(v1, v2, t)
+	}
+	cos := math.Max(-1, math.Min(1, v1.Dot(v2)/(norm1*norm2)))
+	omega := math.Acos(cos)
+	if math.Sin(omega) < 1e-8 {
+		return lerp(v1, v2, t)
+	}
+	scale1 := math.Sin((1-t)*omega) / math.Sin(omega)
+	scale2 := math.Sin(t*omega) / math.Sin(omega)
+	res := make(linalg.Vector, len(v1))
+	for i, x := range v1 {
+		res[i] = x*scale1 + v2[i]*scale2
+	}
+	return res
+}
@@ -0,0 +1,303 @@
+package gans
+
+import (
+	"errors"
+	"math"
+
+	"github.com/unixpickle/autofunc"
+	"github.com/unixpickle/autofunc/seqfunc"
+	"github.com/unixpickle/num-analysis/linalg"
+	"github.com/unixpickle/serializer"
+	"github.com/unixpickle/sgd"
+)
+
+// DefaultPPOClip is the clipping range used when
+// PPO.Clip is 0.
+const DefaultPPOClip = 0.2
+
+func init() {
+	var p PPO
+	serializer.RegisterTypedDeserializer(p.SerializerType(), DeserializePPO)
+}
+
+// PPO configures proximal policy optimization for the
+// generator of a Recurrent, as an alternative to vanilla
+// REINFORCE.
+//
+// Every rollout (a batch of sampled sequences along with
+// their log-probabilities) is used for Epochs generator
+// steps, each of which maximizes a clipped surrogate
+// objective.
+// While a rollout is being reused, the samples passed to
+// Gradient are ignored by the generator.
+type PPO struct {
+	// Epochs is the number of generator steps for which
+	// each rollout is used.
+	// A value of 0 is treated as 1.
+	Epochs int
+
+	// Clip is the maximum amount by which the ratio between
+	// the new and old probabilities of a sampled symbol can
+	// stray from 1 before the objective stops rewarding it.
+	// A value of 0 is treated as DefaultPPOClip.
+	Clip float64
+
+	// Lambda is the decay factor for generalized advantage
+	// estimation, which is used alongside the Recurrent's
+	// DiscountFactor.
+	// A value of 0 is treated as 1.
+	Lambda float64
+
+	// Critic, if non-nil, estimates the value of every
+	// timestep given the sequence so far.
+	// At every timestep, its input is the generator's input
+	// followed by the one-hot vector of the previous symbol
+	// (all zeros at the first timestep), so its input size
+	// is RandomSize plus the number of symbols.
+	// For conditional generators, this is exactly the
+	// generator's input; otherwise, the previous symbol is
+	// appended since the generator does not see it.
+	// The critic must output one value per timestep and is
+	// trained along with the generator to regress the
+	// returns.
+	// Without a critic, all values are estimated as 0.
+	Critic seqfunc.RFunc
+
+	rollout    *rollout
+	criticIn   [][]linalg.Vector
+	logProbs   [][]float64
+	advantages [][]float64
+	returns    [][]float64
+	epoch      int
+}
+
+// DeserializePPO deserializes a PPO configuration.
+func DeserializePPO(d []byte) (*PPO, error) {
+	slice, err := serializer.DeserializeSlice(d)
+	if err != nil {
+		return nil, err
+	}
+	if len(slice) != 3 && len(slice) != 4 {
+		return nil, errors.New("invalid PPO slice")
+	}
+	epochs, ok1 := slice[0].(serializer.Int)
+	clip, ok2 := slice[1].(serializer.Float64)
+	lambda, ok3 := slice[2].(serializer.Float64)
+	if !ok1 || !ok2 || !ok3 {
+		return nil, errors.New("invalid PPO slice")
+	}
+	res := &PPO{Epochs: int(epochs), Clip: float64(clip), Lambda: float64(lambda)}
+	if err := res.checkConfig(); err != nil {
+		return nil, err
+	}
+	if len(slice) == 4 {
+		critic, ok := slice[3].(seqfunc.RFunc)
+		if !ok {
+			return nil, errors.New("invalid PPO slice")
+		}
+		res.Critic = critic
+	}
+	return res, nil
+}
+
+// SerializerType returns the unique ID used to serialize
+// a PPO with the serializer package.
+func (p *PPO) SerializerType() string {
+	return "github.com/unixpickle/gans.PPO"
+}
+
+// Serialize serializes the configuration and the critic.
+// The current rollout is not serialized.
+func (p *PPO) Serialize() ([]byte, error) {
+	s := []serializer.Serializer{
+		serializer.Int(p.Epochs),
+		serializer.Float64(p.Clip),
+		serializer.Float64(p.Lambda),
+	}
+	if p.Critic != nil {
+		critic, ok := p.Critic.(serializer.Serializer)
+		if !ok {
+			return nil, errors.New("PPO critic is not serializable")
+		}
+		s = append(s, critic)
+	}
+	return serializer.SerializeSlice(s)
+}
+
+// gradient computes the generator's gradient (and the
+// critic's, if there is one) for the next PPO step.
+func (p *PPO) gradient(r *Recurrent, s sgd.SampleSet) autofunc.Gradient {
+	epochs := p.Epochs
+	if epochs == 0 {
+		epochs = 1
+	}
+	if err := p.checkConfig(); err != nil {
+		panic(err)
+	}
+	clip := p.Clip
+	if clip == 0 {
+		clip = DefaultPPOClip
+	}
+	if p.rollout == nil || p.epoch >= epochs {
+		p.collect(r, s)
+	}
+	p.epoch++
+
+	params := r.Generator.(sgd.Learner).Parameters()
+	if p.Critic != nil {
+		params = append(params, p.Critic.(sgd.Learner).Parameters()...)
+	}
+	grad := autofunc.NewGradient(params)
+
+	genOut := r.Generator.ApplySeqs(seqfunc.ConstResult(p.rollout.Inputs))
+	upstream := make([][]linalg.Vector, len(p.rollout.Seqs))
+	for i, outSeq := range genOut.OutputSeqs() {
+		upstream[i] = make([]linalg.Vector, len(outSeq))
+		for t, logProbs := range outSeq {
+			upstream[i][t] = make(linalg.Vector, len(logProbs))
+			if t < p.rollout.prefixLen(i) {
+				continue
+			}
+			chosen := p.rollout.Seqs[i][t]
+			ratio := math.Exp(logProbs.Dot(chosen) - p.logProbs[i][t])
+			adv := p.advantages[i][t]
+			if (adv >= 0 && ratio < 1+clip) || (adv < 0 && ratio > 1-clip) {
+				for k, x := range chosen {
+					upstream[i][t][k] = -adv * ratio * x
+				}
+			}
+		}
+	}
+	genOut.PropagateGradient(upstream, grad)
+
+	if p.Critic != nil {
+		values := p.Critic.ApplySeqs(seqfunc.ConstResult(p.criticIn))
+		criticUpstream := make([][]linalg.Vector, len(p.returns))
+		for i, valueSeq := range values.OutputSeqs() {
+			criticUpstream[i] = make([]linalg.Vector, len(valueSeq))
+			for t, value := range valueSeq {
+				criticUpstream[i][t] = linalg.Vector{value[0] - p.returns[i][t]}
+			}
+		}
+		values.PropagateGradient(criticUpstream, grad)
+	}
+
+	return grad
+}
+
+// collect samples a new rollout and computes its
+// advantages and returns.
+func (p *PPO) collect(r *Recurrent, s sgd.SampleSet) {
+	p.epoch = 0
+	p.rollout = r.sampleRollout(s)
+	policy := p.rollout.policy(r.Generator).OutputSeqs()
+	rewards := r.stepRewards(p.rollout.Seqs)
+
+	var values [][]linalg.Vector
+	if p.Critic != nil {
+		p.criticIn = criticInputs(p.rollout, r.Conditional)
+		values = p.Critic.ApplySeqs(seqfunc.ConstResult(p.criticIn)).OutputSeqs()
+	}
+
+	discount := r.DiscountFactor
+	if discount == 0 {
+		discount = 1
+	}
+	lambda := p.Lambda
+	if lambda == 0 {
+		lambda = 1
+	}
+
+	p.logProbs = make([][]float64, len(policy))
+	p.advantages = make([][]float64, len(policy))
+	p.returns = make([][]float64, len(policy))
+	var advSum, advSqSum, advCount float64
+	for i, outSeq := range policy {
+		p.logProbs[i] = make([]float64, len(outSeq))
+		var seqValues []float64
+		if values != nil {
+			seqValues = make([]float64, len(outSeq))
+		}
+		for t, out := range outSeq {
+			p.logProbs[i][t] = out.Dot(p.rollout.Seqs[i][t])
+			if values != nil {
+				seqValues[t] = values[i][t][0]
+			}
+		}
+		p.advantages[i], p.returns[i] = advantageEstimates(rewards[i], seqValues,
+			discount, lambda)
+		for t := p.rollout.prefixLen(i); t < len(outSeq); t++ {
+			adv := p.advantages[i][t]
+			advSum += adv
+			advSqSum += adv * adv
+			advCount++
+		}
+	}
+
+	// Advantages are normalized to reduce the effect of the
+	// discriminator's scale on the step size.
+	if advCount > 0 {
+		mean := advSum / advCount
+		stddev := math.Sqrt(math.Max(advSqSum/advCount-mean*mean, 0))
+		if stddev == 0 {
+			stddev = 1
+		}
+		for _, advSeq := range p.advantages {
+			for t, adv := range advSeq {
+				advSeq[t] = (adv - mean) / stddev
+			}
+		}
+	}
+}
+
+// checkConfig checks that the configuration's fields are
+// in range.
+func (p *PPO) checkConfig() error {
+	if p.Epochs < 0 {
+		return errors.New("PPO epochs must not be negative")
+	} else if p.Clip < 0 {
+		return errors.New("PPO clip must not be negative")
+	} else if p.Lambda < 0 || p.Lambda > 1 {
+		return errors.New("PPO lambda must be in [0, 1]")
+	}
+	return nil
+}
+
+// criticInputs computes the critic's input sequences for
+// a rollout.
+func criticInputs(ro *rollout, conditional bool) [][]linalg.Vector {
+	if conditional {
+		return ro.Inputs
+	}
+	prev := teacherInputs(ro.Seqs)
+	res := make([][]linalg.Vector, len(ro.Inputs))
+	for i, seq := range ro.Inputs {
+		res[i] = make([]linalg.Vector, len(seq))
+		for t, input := range seq {
+			res[i][t] = append(append(linalg.Vector{}, input...), prev[i][t]...)
+		}
+	}
+	return res
+}
+
+// advantageEstimates computes the generalized advantage
+// estimates and the returns for a sequence of rewards.
+// If values is nil, every value is estimated as 0.
+func advantageEstimates(rewards, values []float64, discount,
+	lambda float64) (advantages, returns []float64) {
+	advantages = make([]float64, len(rewards))
+	returns = make([]float64, len(rewards))
+	var nextValue, nextAdv float64
+	for t := len(rewards) - 1; t >= 0; t-- {
+		var value float64
+		if values != nil {
+			value = values[t]
+		}
+		delta := rewards[t] + discount*nextValue - value
+		nextAdv = delta + discount*lambda*nextAdv
+		nextValue = value
+		advantages[t] = nextAdv
+		returns[t] = nextAdv + value
+	}
+	return
+}
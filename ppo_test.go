@@ -0,0 +1,57 @@
+package gans
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/unixpickle/num-analysis/linalg"
+)
+
+func TestAdvantageEstimates(t *testing.T) {
+	rewards := []float64{1, 0, 2}
+	values := []float64{0.5, 1, -1}
+	discount, lambda := 0.9, 0.5
+
+	// Worked by hand from the last timestep backwards:
+	//
+	//	delta2 = 2 - (-1) = 3
+	//	adv2   = 3
+	//	delta1 = 0 + 0.9*(-1) - 1 = -1.9
+	//	adv1   = -1.9 + 0.45*3 = -0.55
+	//	delta0 = 1 + 0.9*1 - 0.5 = 1.4
+	//	adv0   = 1.4 + 0.45*(-0.55) = 1.1525
+	expectedAdv := []float64{1.1525, -0.55, 3}
+	expectedRet := []float64{1.6525, 0.45, 2}
+
+	adv, ret := advantageEstimates(rewards, values, discount, lambda)
+	if !vecsEqual(adv, expectedAdv) {
+		t.Errorf("expected advantages %v but got %v", expectedAdv, adv)
+	}
+	if !vecsEqual(ret, expectedRet) {
+		t.Errorf("expected returns %v but got %v", expectedRet, ret)
+	}
+}
+
+func TestAdvantageEstimatesNoCritic(t *testing.T) {
+	// Without values and with lambda=1, the advantages are
+	// the discounted reward-to-go.
+	adv, ret := advantageEstimates([]float64{1, 2, 4}, nil, 0.5, 1)
+	expected := []float64{3, 4, 4}
+	if !vecsEqual(adv, expected) || !vecsEqual(ret, expected) {
+		t.Errorf("expected %v but got %v and %v", expected, adv, ret)
+	}
+}
+
+func TestCriticInputs(t *testing.T) {
+	ro := &rollout{
+		Inputs: [][]linalg.Vector{{{0.5}, {-0.5}}},
+		Seqs:   [][]linalg.Vector{{{0, 1}, {1, 0}}},
+	}
+	expected := [][]linalg.Vector{{{0.5, 0, 0}, {-0.5, 0, 1}}}
+	if actual := criticInputs(ro, false); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %v but got %v", expected, actual)
+	}
+	if actual := criticInputs(ro, true); !reflect.DeepEqual(actual, ro.Inputs) {
+		t.Errorf("conditional inputs should be unchanged but got %v", actual)
+	}
+}
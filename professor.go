@@ -0,0 +1,324 @@
+package gans
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/unixpickle/autofunc"
+	"github.com/unixpickle/autofunc/seqfunc"
+	"github.com/unixpickle/num-analysis/linalg"
+	"github.com/unixpickle/serializer"
+	"github.com/unixpickle/sgd"
+	"github.com/unixpickle/weakai/neuralnet"
+	"github.com/unixpickle/weakai/rnn"
+	"github.com/unixpickle/weakai/rnn/seqtoseq"
+)
+
+func init() {
+	var p ProfessorForcing
+	serializer.RegisterTypedDeserializer(p.SerializerType(), DeserializeProfessorForcing)
+}
+
+// ProfessorForcing trains an autoregressive sequence model
+// with professor forcing.
+//
+// The generator is trained to maximize the likelihood of
+// the real sequences when it is fed the real symbols
+// (teacher forcing).
+// At the same time, a discriminator learns to tell apart
+// the generator's hidden dynamics when it is teacher
+// forced from its dynamics when it is fed its own samples
+// (free running), and the generator is trained to make
+// its free-running dynamics indistinguishable from its
+// teacher-forced ones.
+type ProfessorForcing struct {
+	GenIterations  int
+	DiscIterations int
+
+	GenTrans  sgd.Transformer
+	DiscTrans sgd.Transformer
+
+	// Generator is the sequence model, whose block must be
+	// an rnn.StackedBlock.
+	// At every timestep, its input is the one-hot vector of
+	// the previous symbol (all zeros at the first timestep)
+	// and its output should be a log-probability
+	// distribution over the next symbol.
+	Generator *rnn.BlockSeqFunc
+
+	// Symbols is the number of symbols, which is the size
+	// of the generator's inputs and outputs.
+	Symbols int
+
+	// HiddenBlocks is the number of leading blocks in the
+	// generator whose outputs make up the behavior which
+	// the discriminator sees.
+	// It must be positive and less than the number of
+	// blocks in the generator.
+	HiddenBlocks int
+
+	// Discriminator classifies sequences of behavior
+	// vectors, outputting values which are meant to be fed
+	// into a sigmoid, with higher values indicating
+	// teacher-forced behavior.
+	Discriminator seqfunc.RFunc
+
+	// DiscMode determines whether the discriminator judges
+	// behavior at every timestep or once per sequence.
+	DiscMode DiscMode
+
+	// AdvWeight scales the adversarial term of the
+	// generator's cost relative to the negative
+	// log-likelihood.
+	AdvWeight float64
+
+	iterIdx int
+}
+
+// DeserializeProfessorForcing deserializes a
+// ProfessorForcing instance.
+func DeserializeProfessorForcing(d []byte) (*ProfessorForcing, error) {
+	slice, err := serializer.DeserializeSlice(d)
+	if err != nil {
+		return nil, err
+	}
+	if len(slice) != 6 {
+		return nil, errors.New("invalid ProfessorForcing slice")
+	}
+	gen, ok1 := slice[0].(*rnn.BlockSeqFunc)
+	disc, ok2 := slice[1].(seqfunc.RFunc)
+	symbols, ok3 := slice[2].(serializer.Int)
+	hiddenBlocks, ok4 := slice[3].(serializer.Int)
+	mode, ok5 := slice[4].(serializer.Int)
+	advWeight, ok6 := slice[5].(serializer.Float64)
+	if !ok1 || !ok2 || !ok3 || !ok4 || !ok5 || !ok6 {
+		return nil, errors.New("invalid ProfessorForcing slice")
+	}
+	res := &ProfessorForcing{
+		Generator:     gen,
+		Symbols:       int(symbols),
+		HiddenBlocks:  int(hiddenBlocks),
+		Discriminator: disc,
+		DiscMode:      DiscMode(mode),
+		AdvWeight:     float64(advWeight),
+	}
+	if err := res.checkGenerator(); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// SerializerType returns the unique ID used to serialize
+// ProfessorForcing instances with the serializer package.
+func (p *ProfessorForcing) SerializerType() string {
+	return "github.com/unixpickle/gans.ProfessorForcing"
+}
+
+// Serialize serializes the instance.
+func (p *ProfessorForcing) Serialize() ([]byte, error) {
+	disc, ok := p.Discriminator.(serializer.Serializer)
+	if !ok {
+		return nil, errors.New("discriminator is not serializable")
+	}
+	return serializer.SerializeSlice([]serializer.Serializer{
+		p.Generator,
+		disc,
+		serializer.Int(p.Symbols),
+		serializer.Int(p.HiddenBlocks),
+		serializer.Int(p.DiscMode),
+		serializer.Float64(p.AdvWeight),
+	})
+}
+
+// Gradient computes the gradient to be descended for the
+// next training step.
+func (p *ProfessorForcing) Gradient(s sgd.SampleSet) autofunc.Gradient {
+	genGrad := autofunc.NewGradient(p.Generator.B.(sgd.Learner).Parameters())
+	discGrad := autofunc.NewGradient(p.Discriminator.(sgd.Learner).Parameters())
+
+	subIdx := p.iterIdx % (p.GenIterations + p.DiscIterations)
+	p.iterIdx++
+
+	if subIdx < p.DiscIterations {
+		p.DiscCost(s).PropagateGradient([]float64{1}, discGrad)
+		if p.DiscTrans != nil {
+			discGrad = p.DiscTrans.Transform(discGrad)
+		}
+	} else {
+		p.GenCost(s).PropagateGradient([]float64{1}, genGrad)
+		if p.GenTrans != nil {
+			genGrad = p.GenTrans.Transform(genGrad)
+		}
+	}
+
+	res := autofunc.Gradient{}
+	for _, g := range []autofunc.Gradient{genGrad, discGrad} {
+		for k, v := range g {
+			res[k] = v
+		}
+	}
+	return res
+}
+
+// DiscCost samples the discriminator's cost for telling
+// apart teacher-forced and free-running behavior.
+func (p *ProfessorForcing) DiscCost(s sgd.SampleSet) autofunc.Result {
+	front, _ := p.splitGenerator()
+	realSeqs := p.realSequences(s)
+	tfBehavior := front.ApplySeqs(seqfunc.ConstResult(teacherInputs(realSeqs)))
+	frBehavior := front.ApplySeqs(seqfunc.ConstResult(p.freeRunInputs(realSeqs)))
+	return seqDiscCost(p.discriminate, nil, tfBehavior.OutputSeqs(),
+		frBehavior.OutputSeqs())
+}
+
+// GenCost samples the generator's cost, which is the
+// negative log-likelihood of the real sequences plus
+// AdvWeight times the cross-entropy cost of the
+// discriminator when free-running behavior is labeled as
+// teacher-forced.
+func (p *ProfessorForcing) GenCost(s sgd.SampleSet) autofunc.Result {
+	realSeqs := p.realSequences(s)
+	nll := p.nll(realSeqs)
+	if p.AdvWeight == 0 {
+		return nll
+	}
+
+	front, _ := p.splitGenerator()
+	frBehavior := front.ApplySeqs(seqfunc.ConstResult(p.freeRunInputs(realSeqs)))
+	costFunc := func(a autofunc.Result) autofunc.Result {
+		return neuralnet.SigmoidCECost{}.Cost([]float64{1}, a)
+	}
+	advCost := seqfunc.AddAll(seqfunc.Map(p.discriminate(frBehavior), costFunc))
+	return autofunc.Add(nll, autofunc.Scale(advCost, p.AdvWeight))
+}
+
+// NLL computes the negative log-likelihood of the
+// sequences in s under teacher forcing.
+func (p *ProfessorForcing) NLL(s sgd.SampleSet) float64 {
+	return p.nll(p.realSequences(s)).Output()[0]
+}
+
+// Generate samples a sequence of the given length from
+// the free-running generator and returns the index of
+// each symbol.
+func (p *ProfessorForcing) Generate(length int) []int {
+	runner := &rnn.Runner{Block: p.Generator.B}
+	prev := make(linalg.Vector, p.Symbols)
+	res := make([]int, length)
+	for i := range res {
+		res[i] = sampleVector(runner.StepTime(prev))
+		prev = OneHotSeq([]int{res[i]}, p.Symbols)[0]
+	}
+	return res
+}
+
+func (p *ProfessorForcing) nll(realSeqs [][]linalg.Vector) autofunc.Result {
+	out := p.Generator.ApplySeqs(seqfunc.ConstResult(teacherInputs(realSeqs)))
+	var sum float64
+	for i, outSeq := range out.OutputSeqs() {
+		for t, logProbs := range outSeq {
+			sum -= logProbs.Dot(realSeqs[i][t])
+		}
+	}
+	return &nllResult{Input: out, Targets: realSeqs, OutputVec: linalg.Vector{sum}}
+}
+
+// freeRunInputs runs the generator freely for as many
+// timesteps as each real sequence has, returning the
+// inputs which it was fed.
+func (p *ProfessorForcing) freeRunInputs(realSeqs [][]linalg.Vector) [][]linalg.Vector {
+	res := make([][]linalg.Vector, len(realSeqs))
+	for i, seq := range realSeqs {
+		runner := &rnn.Runner{Block: p.Generator.B}
+		prev := make(linalg.Vector, p.Symbols)
+		for _ = range seq {
+			res[i] = append(res[i], prev)
+			prev = OneHotSeq([]int{sampleVector(runner.StepTime(prev))}, p.Symbols)[0]
+		}
+	}
+	return res
+}
+
+// checkGenerator checks that the generator is a stack of
+// blocks which HiddenBlocks splits in two.
+func (p *ProfessorForcing) checkGenerator() error {
+	stack, ok := p.Generator.B.(rnn.StackedBlock)
+	if !ok {
+		return errors.New("ProfessorForcing generator must be a stacked block")
+	}
+	if p.HiddenBlocks <= 0 || p.HiddenBlocks >= len(stack) {
+		return fmt.Errorf("ProfessorForcing hidden blocks must be in [1, %d]",
+			len(stack)-1)
+	}
+	return nil
+}
+
+// splitGenerator splits the generator into the blocks
+// which produce behavior and the remaining blocks.
+// It panics if the generator cannot be split.
+func (p *ProfessorForcing) splitGenerator() (front, back *rnn.BlockSeqFunc) {
+	if err := p.checkGenerator(); err != nil {
+		panic(err)
+	}
+	stack := p.Generator.B.(rnn.StackedBlock)
+	return &rnn.BlockSeqFunc{B: stack[:p.HiddenBlocks]},
+		&rnn.BlockSeqFunc{B: stack[p.HiddenBlocks:]}
+}
+
+func (p *ProfessorForcing) discriminate(in seqfunc.Result) seqfunc.Result {
+	return p.DiscMode.pool(p.Discriminator.ApplySeqs(in))
+}
+
+func (p *ProfessorForcing) realSequences(s sgd.SampleSet) [][]linalg.Vector {
+	var res [][]linalg.Vector
+	for i := 0; i < s.Len(); i++ {
+		res = append(res, s.GetSample(i).(seqtoseq.Sample).Inputs)
+	}
+	return res
+}
+
+// teacherInputs shifts one-hot sequences forward by one
+// timestep, starting each with a zero vector.
+func teacherInputs(seqs [][]linalg.Vector) [][]linalg.Vector {
+	res := make([][]linalg.Vector, len(seqs))
+	for i, seq := range seqs {
+		for t := range seq {
+			if t == 0 {
+				res[i] = append(res[i], make(linalg.Vector, len(seq[0])))
+			} else {
+				res[i] = append(res[i], seq[t-1])
+			}
+		}
+	}
+	return res
+}
+
+// nllResult is the negative log-likelihood of one-hot
+// targets under a sequence of log-probability vectors.
+type nllResult struct {
+	Input     seqfunc.Result
+	Targets   [][]linalg.Vector
+	OutputVec linalg.Vector
+}
+
+func (n *nllResult) Output() linalg.Vector {
+	return n.OutputVec
+}
+
+func (n *nllResult) Constant(g autofunc.Gradient) bool {
+	return false
+}
+
+func (n *nllResult) PropagateGradient(upstream linalg.Vector, grad autofunc.Gradient) {
+	downstream := make([][]linalg.Vector, len(n.Targets))
+	for i, seq := range n.Targets {
+		downstream[i] = make([]linalg.Vector, len(seq))
+		for t, target := range seq {
+			downstream[i][t] = make(linalg.Vector, len(target))
+			for k, x := range target {
+				downstream[i][t][k] = -upstream[0] * x
+			}
+		}
+	}
+	n.Input.PropagateGradient(downstream, grad)
+}
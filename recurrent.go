@@ -1,6 +1,7 @@
 package gans
 
 import (
+	"errors"
 	"math"
 	"math/rand"
 
@@ -45,21 +46,120 @@ type Recurrent struct {
 	// A value of 0 is treated as 1.
 	DiscountFactor float64
 
+	// Noise, if non-nil, configures label smoothing,
+	// label flipping, and instance noise for the
+	// discriminator.
+	Noise *DiscNoise
+
+	// Replay, if non-nil, stores past sampled sequences and
+	// mixes them into the discriminator's batches.
+	Replay *ReplayBuffer
+
+	// DiscMode determines whether the discriminator judges
+	// sequences at every timestep or once per sequence.
+	// In the latter case, the generator is only rewarded at
+	// the end of each sequence, and the reward reaches
+	// earlier timesteps through DiscountFactor.
+	DiscMode DiscMode
+
+	// PPO, if non-nil, makes the generator use proximal
+	// policy optimization instead of REINFORCE.
+	// If PPO has a critic, the critic's gradient is
+	// included in the generator's gradient.
+	PPO *PPO
+
+	// Vocab, if non-nil, is the vocabulary of the symbols
+	// which the generator produces.
+	// It is saved with the model so that generated
+	// sequences can be decoded after loading.
+	Vocab Vocabulary
+
+	// Conditional, if true, makes the generator
+	// autoregressive so that it can continue prefixes.
+	// At every timestep, the generator's input is a random
+	// vector followed by the one-hot vector of the previous
+	// symbol (all zeros at the first timestep), so its input
+	// size must be RandomSize plus the number of symbols.
+	// The generator must be an *rnn.BlockSeqFunc.
+	Conditional bool
+
+	// PrefixLen is the maximum length of the real prefixes
+	// which the generator continues during conditional
+	// training.
+	// Every training sequence is split at a random point no
+	// later than PrefixLen, and the generator is fed the
+	// real symbols before that point.
+	PrefixLen int
+
 	iterIdx int
 }
 
 // DeserializeRecurrent deserializes a Recurrent instance.
 func DeserializeRecurrent(d []byte) (*Recurrent, error) {
-	res := &Recurrent{}
-	var randomSize serializer.Int
-	var discount serializer.Float64
-	err := serializer.DeserializeAny(d, &res.Discriminator, &res.Generator,
-		&randomSize, &discount)
+	slice, err := serializer.DeserializeSlice(d)
 	if err != nil {
 		return nil, err
 	}
-	res.RandomSize = int(randomSize)
-	res.DiscountFactor = float64(discount)
+	if len(slice) != 4 && len(slice) != 6 && len(slice) != 7 &&
+		len(slice) != 9 && len(slice) != 10 && len(slice) != 11 {
+		return nil, errors.New("invalid Recurrent slice")
+	}
+	disc, ok1 := slice[0].(seqfunc.RFunc)
+	gen, ok2 := slice[1].(seqfunc.RFunc)
+	randomSize, ok3 := slice[2].(serializer.Int)
+	discount, ok4 := slice[3].(serializer.Float64)
+	if !ok1 || !ok2 || !ok3 || !ok4 {
+		return nil, errors.New("invalid Recurrent slice")
+	}
+	res := &Recurrent{
+		Discriminator:  disc,
+		Generator:      gen,
+		RandomSize:     int(randomSize),
+		DiscountFactor: float64(discount),
+	}
+	if len(slice) >= 6 {
+		noise, ok1 := slice[4].(*DiscNoise)
+		replay, ok2 := slice[5].(*ReplayBuffer)
+		if !ok1 || !ok2 {
+			return nil, errors.New("invalid Recurrent slice")
+		}
+		res.Noise = noise
+		if replay.Capacity > 0 {
+			res.Replay = replay
+		}
+	}
+	if len(slice) >= 7 {
+		vocab, ok := slice[6].(Vocabulary)
+		if !ok {
+			return nil, errors.New("invalid Recurrent slice")
+		}
+		if vocab.Size() > 0 {
+			res.Vocab = vocab
+		}
+	}
+	if len(slice) >= 9 {
+		conditional, ok1 := slice[7].(serializer.Int)
+		prefixLen, ok2 := slice[8].(serializer.Int)
+		if !ok1 || !ok2 {
+			return nil, errors.New("invalid Recurrent slice")
+		}
+		res.Conditional = conditional != 0
+		res.PrefixLen = int(prefixLen)
+	}
+	if len(slice) >= 10 {
+		mode, ok := slice[9].(serializer.Int)
+		if !ok {
+			return nil, errors.New("invalid Recurrent slice")
+		}
+		res.DiscMode = DiscMode(mode)
+	}
+	if len(slice) == 11 {
+		ppo, ok := slice[10].(*PPO)
+		if !ok {
+			return nil, errors.New("invalid Recurrent slice")
+		}
+		res.PPO = ppo
+	}
 	return res, nil
 }
 
@@ -71,9 +171,36 @@ func (r *Recurrent) SerializerType() string {
 
 // Serialize serializes the instance.
 func (r *Recurrent) Serialize() ([]byte, error) {
-	return serializer.SerializeAny(r.Discriminator, r.Generator,
+	noise := r.Noise
+	if noise == nil {
+		noise = &DiscNoise{}
+	}
+	replay := r.Replay
+	if replay == nil {
+		replay = &ReplayBuffer{}
+	}
+	vocab := r.Vocab
+	if vocab == nil {
+		vocab = NewAlphabetVocab("")
+	}
+	var conditional serializer.Int
+	if r.Conditional {
+		conditional = 1
+	}
+	fields := []interface{}{r.Discriminator, r.Generator,
 		serializer.Int(r.RandomSize),
-		serializer.Float64(r.DiscountFactor))
+		serializer.Float64(r.DiscountFactor),
+		noise, replay, vocab, conditional,
+		serializer.Int(r.PrefixLen),
+		serializer.Int(r.DiscMode)}
+	// Unlike the other optional fields, PPO is omitted
+	// rather than replaced by a placeholder when it is nil,
+	// since nil means REINFORCE while a zero PPO is a valid
+	// configuration which enables PPO with default settings.
+	if r.PPO != nil {
+		fields = append(fields, r.PPO)
+	}
+	return serializer.SerializeAny(fields...)
 }
 
 // Gradient computes the gradient to be descended for the
@@ -86,15 +213,22 @@ func (r *Recurrent) Gradient(s sgd.SampleSet) autofunc.Gradient {
 	r.iterIdx++
 
 	if subIdx < r.DiscIterations {
-		r.DiscCost(s).PropagateGradient([]float64{1}, discGrad)
+		genSeqs := r.sampleRollout(s).Seqs
+		if r.Replay != nil {
+			genSeqs = r.Replay.Mix(genSeqs)
+		}
+		r.discCost(s, genSeqs).PropagateGradient([]float64{1}, discGrad)
+		r.Noise.step()
 		if r.DiscTrans != nil {
 			discGrad = r.DiscTrans.Transform(discGrad)
 		}
 	} else {
-		genIn := r.generatorSeed(s)
-		genOut := r.Generator.ApplySeqs(genIn)
-		upstream := r.sampleReward(genOut)
-		genOut.PropagateGradient(upstream, genGrad)
+		if r.PPO != nil {
+			genGrad = r.PPO.gradient(r, s)
+		} else {
+			ro := r.sampleRollout(s)
+			ro.policy(r.Generator).PropagateGradient(r.rolloutRewards(ro), genGrad)
+		}
 		if r.GenTrans != nil {
 			genGrad = r.GenTrans.Transform(genGrad)
 		}
@@ -112,33 +246,19 @@ func (r *Recurrent) Gradient(s sgd.SampleSet) autofunc.Gradient {
 
 // DiscCost samples the discriminator cost.
 func (r *Recurrent) DiscCost(s sgd.SampleSet) autofunc.Result {
-	genIn := r.generatorSeed(s)
-	genOut := r.Generator.ApplySeqs(genIn)
-	sv := r.sampleGenSeq(genOut)
-	genClassifications := r.Discriminator.ApplySeqs(seqfunc.ConstResult(sv))
-
-	realIn := r.inputSequences(s)
-	realClassifications := r.Discriminator.ApplySeqs(realIn)
-
-	genCostFunc := func(a autofunc.Result) autofunc.Result {
-		return neuralnet.SigmoidCECost{}.Cost([]float64{0}, a)
-	}
-	realCostFunc := func(a autofunc.Result) autofunc.Result {
-		return neuralnet.SigmoidCECost{}.Cost([]float64{1}, a)
-	}
+	return r.discCost(s, r.sampleRollout(s).Seqs)
+}
 
-	return autofunc.Add(
-		seqfunc.AddAll(seqfunc.Map(realClassifications, realCostFunc)),
-		seqfunc.AddAll(seqfunc.Map(genClassifications, genCostFunc)),
-	)
+// discCost computes the discriminator cost on the real
+// samples in s and the given generated sequences.
+func (r *Recurrent) discCost(s sgd.SampleSet, genSeqs [][]linalg.Vector) autofunc.Result {
+	return seqDiscCost(r.discriminate, r.Noise, r.inputSequences(s), genSeqs)
 }
 
 // GenReward samples the generator reward.
 func (r *Recurrent) GenReward(s sgd.SampleSet) float64 {
-	genIn := r.generatorSeed(s)
-	genOut := r.Generator.ApplySeqs(genIn)
 	var sum float64
-	for _, x := range r.sampleReward(genOut) {
+	for _, x := range r.rolloutRewards(r.sampleRollout(s)) {
 		for _, y := range x {
 			for _, k := range y {
 				sum += k
@@ -148,25 +268,92 @@ func (r *Recurrent) GenReward(s sgd.SampleSet) float64 {
 	return sum
 }
 
-// sampleReward samples from the policy's log-probability
-// outputs.
-// The resulting sequences are the same "shape" as policyOut,
-// but the only non-zero entries are in the positions where
-// the sampled character was chosen, and those entries are
-// equal to -1 times the cumulative reward from that point
-// onward.
-func (r *Recurrent) sampleReward(policyOut seqfunc.Result) [][]linalg.Vector {
-	sv := r.sampleGenSeq(policyOut)
-	out := seqfunc.Map(r.Discriminator.ApplySeqs(seqfunc.ConstResult(sv)),
-		autofunc.Sigmoid{}.Apply).OutputSeqs()
+// Generate samples a sequence of the given length from
+// the generator and returns the index of each symbol.
+func (r *Recurrent) Generate(length int) []int {
+	if r.Conditional {
+		return r.Continue(nil, length)
+	}
+	seed := make([]linalg.Vector, length)
+	for i := range seed {
+		seed[i] = make(linalg.Vector, r.RandomSize)
+		for j := range seed[i] {
+			seed[i][j] = rand.NormFloat64()
+		}
+	}
+	out := r.Generator.ApplySeqs(seqfunc.ConstResult([][]linalg.Vector{seed}))
+	res := make([]int, length)
+	for i, vec := range out.OutputSeqs()[0] {
+		res[i] = sampleVector(vec)
+	}
+	return res
+}
+
+// GenerateText samples a sequence of the given length
+// from the generator and decodes it with Vocab.
+// If Vocab is nil, as it is for models saved before
+// vocabularies were stored, ByteVocab is used.
+func (r *Recurrent) GenerateText(length int) string {
+	vocab := r.Vocab
+	if vocab == nil {
+		vocab = ByteVocab{}
+	}
+	return DecodeText(vocab, r.Generate(length))
+}
+
+// Scores computes the discriminator's probability that a
+// sequence is real at every timestep.
+// If DiscMode judges entire sequences, every timestep
+// gets the same score.
+func (r *Recurrent) Scores(seq []linalg.Vector) []float64 {
+	out := r.discriminate(seqfunc.ConstResult([][]linalg.Vector{seq}))
+	outSeq := out.OutputSeqs()[0]
+	res := make([]float64, len(seq))
+	for i := range res {
+		if r.DiscMode == PerStepDisc {
+			res[i] = 1 / (1 + math.Exp(-outSeq[i][0]))
+		} else {
+			res[i] = 1 / (1 + math.Exp(-outSeq[0][0]))
+		}
+	}
+	return res
+}
+
+// OneHotSeq converts a sequence of symbol indices into
+// one-hot vectors of the given size.
+func OneHotSeq(symbols []int, size int) []linalg.Vector {
+	res := make([]linalg.Vector, len(symbols))
+	for i, symbol := range symbols {
+		res[i] = make(linalg.Vector, size)
+		res[i][symbol] = 1
+	}
+	return res
+}
+
+// rolloutRewards computes the upstream gradient for the
+// generator's log-probability outputs in a rollout.
+// The resulting sequences are the same "shape" as the
+// sampled sequences, but the only non-zero entries are in
+// the positions where the sampled symbol was chosen, and
+// those entries are equal to -1 times the cumulative
+// reward from that point onward.
+// Symbols in real prefixes receive no gradient.
+func (r *Recurrent) rolloutRewards(ro *rollout) [][]linalg.Vector {
+	sv := ro.Seqs
+	rewards := r.stepRewards(sv)
 
 	cumulativeRewards := make([][]linalg.Vector, len(sv))
-	for i, outSeq := range out {
+	for i, stepRewards := range rewards {
 		var cumulative float64
-		cr := make([]linalg.Vector, len(outSeq))
-		for j := len(outSeq) - 1; j >= 0; j-- {
-			cumulative += outSeq[j][0]
-			cr[j] = sv[i][j].Scale(-cumulative)
+		cr := make([]linalg.Vector, len(stepRewards))
+		for j := len(stepRewards) - 1; j >= 0; j-- {
+			cumulative += stepRewards[j]
+			cr[j] = make(linalg.Vector, len(sv[i][j]))
+			if j >= ro.prefixLen(i) {
+				for k, x := range sv[i][j] {
+					cr[j][k] = -cumulative * x
+				}
+			}
 			if r.DiscountFactor != 0 {
 				cumulative *= r.DiscountFactor
 			}
@@ -177,6 +364,39 @@ func (r *Recurrent) sampleReward(policyOut seqfunc.Result) [][]linalg.Vector {
 	return cumulativeRewards
 }
 
+// stepRewards computes the discriminator's reward for
+// every timestep of every sequence.
+func (r *Recurrent) stepRewards(seqs [][]linalg.Vector) [][]float64 {
+	out := seqfunc.Map(r.discriminate(seqfunc.ConstResult(seqs)),
+		autofunc.Sigmoid{}.Apply).OutputSeqs()
+	lengths := make([]int, len(seqs))
+	for i, seq := range seqs {
+		lengths[i] = len(seq)
+	}
+	return r.DiscMode.stepRewards(out, lengths)
+}
+
+// discriminate applies the discriminator and pools its
+// outputs according to DiscMode.
+func (r *Recurrent) discriminate(in seqfunc.Result) seqfunc.Result {
+	return r.DiscMode.pool(r.Discriminator.ApplySeqs(in))
+}
+
+// sampleRollout samples generated sequences for every
+// sequence in s.
+func (r *Recurrent) sampleRollout(s sgd.SampleSet) *rollout {
+	if r.Conditional {
+		return r.conditionalRollout(s)
+	}
+	genIn := generatorSeed(s, r.RandomSize)
+	genOut := r.Generator.ApplySeqs(genIn)
+	return &rollout{
+		Inputs:    genIn.OutputSeqs(),
+		Seqs:      r.sampleGenSeq(genOut),
+		PolicyOut: genOut,
+	}
+}
+
 func (r *Recurrent) sampleGenSeq(policyOut seqfunc.Result) [][]linalg.Vector {
 	var sampledVecs [][]linalg.Vector
 	for _, seq := range policyOut.OutputSeqs() {
@@ -192,12 +412,15 @@ func (r *Recurrent) sampleGenSeq(policyOut seqfunc.Result) [][]linalg.Vector {
 	return sampledVecs
 }
 
-func (r *Recurrent) generatorSeed(s sgd.SampleSet) seqfunc.Result {
+// generatorSeed creates random generator inputs with
+// randomSize components for every timestep of every
+// sequence in s.
+func generatorSeed(s sgd.SampleSet, randomSize int) seqfunc.Result {
 	var res [][]linalg.Vector
 	for i := 0; i < s.Len(); i++ {
 		var inSeq []linalg.Vector
 		for _ = range s.GetSample(i).(seqtoseq.Sample).Inputs {
-			inVec := make(linalg.Vector, r.RandomSize)
+			inVec := make(linalg.Vector, randomSize)
 			for j := range inVec {
 				inVec[j] = rand.NormFloat64()
 			}
@@ -208,12 +431,60 @@ func (r *Recurrent) generatorSeed(s sgd.SampleSet) seqfunc.Result {
 	return seqfunc.ConstResult(res)
 }
 
-func (r *Recurrent) inputSequences(s sgd.SampleSet) seqfunc.Result {
+func (r *Recurrent) inputSequences(s sgd.SampleSet) [][]linalg.Vector {
 	var res [][]linalg.Vector
 	for i := 0; i < s.Len(); i++ {
 		res = append(res, s.GetSample(i).(seqtoseq.Sample).Inputs)
 	}
-	return seqfunc.ConstResult(res)
+	return res
+}
+
+// seqDiscCost computes the cross-entropy cost of a
+// sequence discriminator on real and generated sequences,
+// applying the noise configuration.
+func seqDiscCost(disc func(seqfunc.Result) seqfunc.Result, noise *DiscNoise, realSeqs,
+	genSeqs [][]linalg.Vector) autofunc.Result {
+	// Sequences are grouped by their (possibly flipped)
+	// labels so that each group has a single target.
+	var posSeqs, negSeqs [][]linalg.Vector
+	for _, group := range []struct {
+		seqs [][]linalg.Vector
+		real bool
+	}{{realSeqs, true}, {genSeqs, false}} {
+		for _, seq := range group.seqs {
+			noisy := make([]linalg.Vector, len(seq))
+			for i, vec := range seq {
+				noisy[i] = noise.noisyVec(vec)
+			}
+			if noise.target(group.real) != 0 {
+				posSeqs = append(posSeqs, noisy)
+			} else {
+				negSeqs = append(negSeqs, noisy)
+			}
+		}
+	}
+
+	var cost autofunc.Result
+	for _, group := range []struct {
+		seqs   [][]linalg.Vector
+		target float64
+	}{{posSeqs, noise.realTarget()}, {negSeqs, 0}} {
+		if len(group.seqs) == 0 {
+			continue
+		}
+		target := group.target
+		costFunc := func(a autofunc.Result) autofunc.Result {
+			return neuralnet.SigmoidCECost{}.Cost([]float64{target}, a)
+		}
+		classifications := disc(seqfunc.ConstResult(group.seqs))
+		groupCost := seqfunc.AddAll(seqfunc.Map(classifications, costFunc))
+		if cost == nil {
+			cost = groupCost
+		} else {
+			cost = autofunc.Add(cost, groupCost)
+		}
+	}
+	return cost
 }
 
 func sampleVector(v linalg.Vector) int {
@@ -0,0 +1,150 @@
+package gans
+
+import (
+	"errors"
+	"math/rand"
+
+	"github.com/unixpickle/num-analysis/linalg"
+	"github.com/unixpickle/serializer"
+)
+
+func init() {
+	var r ReplayBuffer
+	serializer.RegisterTypedDeserializer(r.SerializerType(), DeserializeReplayBuffer)
+}
+
+// ReplayBuffer stores past generator outputs so that they
+// can be mixed into future discriminator batches.
+//
+// Each entry is a sequence of vectors.
+// Feedforward models like FM store each generated sample
+// as a sequence of length one.
+type ReplayBuffer struct {
+	// Capacity is the maximum number of stored entries.
+	Capacity int
+
+	// MixRatio is the fraction of each generated batch
+	// which is replaced by entries from the buffer.
+	MixRatio float64
+
+	// Checkpoint indicates whether the buffer's entries
+	// should be serialized along with its configuration.
+	Checkpoint bool
+
+	// Entries contains the stored generator outputs.
+	Entries [][]linalg.Vector
+
+	// Seen is the total number of entries ever offered to
+	// the buffer, used for reservoir sampling.
+	Seen int
+}
+
+// DeserializeReplayBuffer deserializes a ReplayBuffer.
+func DeserializeReplayBuffer(d []byte) (*ReplayBuffer, error) {
+	slice, err := serializer.DeserializeSlice(d)
+	if err != nil {
+		return nil, err
+	}
+	if len(slice) < 5 {
+		return nil, errors.New("invalid ReplayBuffer slice")
+	}
+	capacity, ok1 := slice[0].(serializer.Int)
+	ratio, ok2 := slice[1].(serializer.Float64)
+	checkpoint, ok3 := slice[2].(serializer.Int)
+	seen, ok4 := slice[3].(serializer.Int)
+	count, ok5 := slice[4].(serializer.Int)
+	if !ok1 || !ok2 || !ok3 || !ok4 || !ok5 || len(slice) != 5+2*int(count) {
+		return nil, errors.New("invalid ReplayBuffer slice")
+	}
+	res := &ReplayBuffer{
+		Capacity:   int(capacity),
+		MixRatio:   float64(ratio),
+		Checkpoint: checkpoint != 0,
+		Seen:       int(seen),
+	}
+	for i := 0; i < int(count); i++ {
+		seqLen, ok1 := slice[5+2*i].(serializer.Int)
+		data, ok2 := slice[6+2*i].(serializer.Float64Slice)
+		if !ok1 || !ok2 || seqLen < 0 || (seqLen == 0 && len(data) > 0) ||
+			(seqLen > 0 && len(data)%int(seqLen) != 0) {
+			return nil, errors.New("invalid ReplayBuffer slice")
+		}
+		seq := make([]linalg.Vector, int(seqLen))
+		for j := range seq {
+			vecSize := len(data) / int(seqLen)
+			seq[j] = linalg.Vector(data[j*vecSize : (j+1)*vecSize])
+		}
+		res.Entries = append(res.Entries, seq)
+	}
+	return res, nil
+}
+
+// Mix replaces a MixRatio fraction of the generated batch
+// with random entries from the buffer, then offers the
+// original generated entries to the buffer.
+//
+// The result is a new slice; gen is not modified.
+func (r *ReplayBuffer) Mix(gen [][]linalg.Vector) [][]linalg.Vector {
+	res := append([][]linalg.Vector{}, gen...)
+	if len(r.Entries) > 0 {
+		numReplay := int(r.MixRatio*float64(len(gen)) + 0.5)
+		if numReplay > len(gen) {
+			numReplay = len(gen)
+		}
+		for _, idx := range rand.Perm(len(gen))[:numReplay] {
+			res[idx] = r.Entries[rand.Intn(len(r.Entries))]
+		}
+	}
+	for _, entry := range gen {
+		r.Add(entry)
+	}
+	return res
+}
+
+// Add offers an entry to the buffer.
+// Once the buffer is full, reservoir sampling is used so
+// that the buffer holds a uniform sample of every entry
+// that has been offered.
+func (r *ReplayBuffer) Add(entry []linalg.Vector) {
+	r.Seen++
+	if len(r.Entries) < r.Capacity {
+		r.Entries = append(r.Entries, entry)
+	} else if idx := rand.Intn(r.Seen); idx < len(r.Entries) {
+		r.Entries[idx] = entry
+	}
+}
+
+// SerializerType returns the unique ID used to serialize
+// a ReplayBuffer with the serializer package.
+func (r *ReplayBuffer) SerializerType() string {
+	return "github.com/unixpickle/gans.ReplayBuffer"
+}
+
+// Serialize serializes the buffer's configuration and,
+// if r.Checkpoint is set, its entries.
+func (r *ReplayBuffer) Serialize() ([]byte, error) {
+	var checkpoint serializer.Int
+	var entries [][]linalg.Vector
+	seen := r.Seen
+	if r.Checkpoint {
+		checkpoint = 1
+		entries = r.Entries
+	} else {
+		seen = 0
+	}
+	slice := []serializer.Serializer{
+		serializer.Int(r.Capacity),
+		serializer.Float64(r.MixRatio),
+		checkpoint,
+		serializer.Int(seen),
+		serializer.Int(len(entries)),
+	}
+	for _, seq := range entries {
+		var data serializer.Float64Slice
+		for _, vec := range seq {
+			data = append(data, vec...)
+		}
+		slice = append(slice, serializer.Int(len(seq)), data)
+	}
+	return serializer.SerializeSlice(slice)
+}
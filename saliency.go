@@ -0,0 +1,121 @@
+package gans
+
+import (
+	"math"
+
+	"github.com/unixpickle/autofunc"
+	"github.com/unixpickle/num-analysis/linalg"
+	"github.com/unixpickle/weakai/neuralnet"
+)
+
+// Saliency computes the gradient of the discriminator's
+// output with respect to its input for a sample.
+func (f *FM) Saliency(sample linalg.Vector) linalg.Vector {
+	in := &autofunc.Variable{Vector: sample}
+	grad := autofunc.NewGradient([]*autofunc.Variable{in})
+	f.Discriminator.Apply(in).PropagateGradient(linalg.Vector{1}, grad)
+	return grad[in]
+}
+
+// Saliency computes the gradient of the sum of the
+// discriminator's outputs (pooled according to DiscMode)
+// with respect to every input vector in a sequence.
+func (r *Recurrent) Saliency(seq []linalg.Vector) []linalg.Vector {
+	in := &gradientSeqs{
+		seqs: [][]linalg.Vector{seq},
+		grad: [][]linalg.Vector{make([]linalg.Vector, len(seq))},
+	}
+	for i, vec := range seq {
+		in.grad[0][i] = make(linalg.Vector, len(vec))
+	}
+	out := r.discriminate(in)
+	var upstream [][]linalg.Vector
+	for _, outSeq := range out.OutputSeqs() {
+		upSeq := make([]linalg.Vector, len(outSeq))
+		for i, vec := range outSeq {
+			upSeq[i] = make(linalg.Vector, len(vec))
+			for j := range upSeq[i] {
+				upSeq[i][j] = 1
+			}
+		}
+		upstream = append(upstream, upSeq)
+	}
+	out.PropagateGradient(upstream, autofunc.Gradient{})
+	return in.grad[0]
+}
+
+// SaliencyScores converts per-timestep gradients into
+// scores between 0 and 1, proportional to the magnitude
+// of each gradient.
+// The result can be used for TextSample.Scores.
+func SaliencyScores(grads []linalg.Vector) []float64 {
+	res := make([]float64, len(grads))
+	var max float64
+	for i, g := range grads {
+		res[i] = math.Sqrt(g.Dot(g))
+		max = math.Max(max, res[i])
+	}
+	if max > 0 {
+		for i := range res {
+			res[i] /= max
+		}
+	}
+	return res
+}
+
+// SaliencyTensor overlays a saliency map on an image,
+// producing an RGB image which can be passed to
+// GridSample.
+//
+// The image is shown in grayscale (using its first
+// channel if it is not grayscale) and the magnitude of
+// the saliency at each pixel, normalized by the largest
+// magnitude, is shown in red.
+// Values in img should be in the range [0, 1].
+func SaliencyTensor(img *neuralnet.Tensor3, saliency linalg.Vector) *neuralnet.Tensor3 {
+	magnitudes := make([]float64, img.Width*img.Height)
+	var max float64
+	for i := range magnitudes {
+		for z := 0; z < img.Depth; z++ {
+			magnitudes[i] = math.Max(magnitudes[i], math.Abs(saliency[i*img.Depth+z]))
+		}
+		max = math.Max(max, magnitudes[i])
+	}
+
+	res := neuralnet.NewTensor3(img.Width, img.Height, 3)
+	for y := 0; y < img.Height; y++ {
+		for x := 0; x < img.Width; x++ {
+			gray := math.Max(0, math.Min(1, img.Get(x, y, 0)))
+			var heat float64
+			if max > 0 {
+				heat = magnitudes[y*img.Width+x] / max
+			}
+			res.Set(x, y, 0, gray*(1-heat)+heat)
+			res.Set(x, y, 1, gray*(1-heat))
+			res.Set(x, y, 2, gray*(1-heat))
+		}
+	}
+	return res
+}
+
+// gradientSeqs is a seqfunc.Result which accumulates the
+// gradients propagated to it.
+type gradientSeqs struct {
+	seqs [][]linalg.Vector
+	grad [][]linalg.Vector
+}
+
+func (g *gradientSeqs) OutputSeqs() [][]linalg.Vector {
+	return g.seqs
+}
+
+func (g *gradientSeqs) PropagateGradient(upstream [][]linalg.Vector,
+	grad autofunc.Gradient) {
+	for i, seq := range upstream {
+		for j, vec := range seq {
+			for k, x := range vec {
+				g.grad[i][j][k] += x
+			}
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package gans
+
+import (
+	"image"
+	"image/color"
+
+	"github.com/unixpickle/num-analysis/linalg"
+)
+
+var (
+	ScatterBackground = color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}
+	ScatterAxisColor  = color.RGBA{R: 0xc0, G: 0xc0, B: 0xc0, A: 0xff}
+	ScatterPointColor = color.RGBA{R: 0x30, G: 0x60, B: 0xc0, A: 0xff}
+)
+
+// ScatterSample samples 2D points from a generator and
+// draws them as a scatter plot on a square image.
+//
+// The image spans from -scale to scale along both axes.
+// Points outside this range are not drawn.
+func ScatterSample(size, count int, scale float64, gen func() linalg.Vector) image.Image {
+	points := make([]linalg.Vector, count)
+	for i := range points {
+		points[i] = gen()
+	}
+	return ScatterPlot(size, scale, [][]linalg.Vector{points},
+		[]color.Color{ScatterPointColor})
+}
+
+// ScatterPlot draws sets of 2D points on a square image,
+// using a different color for each set.
+// Later sets are drawn on top of earlier ones.
+//
+// The image spans from -scale to scale along both axes.
+// Points outside this range are not drawn.
+func ScatterPlot(size int, scale float64, sets [][]linalg.Vector,
+	colors []color.Color) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, size, size))
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			if x == size/2 || y == size/2 {
+				img.Set(x, y, ScatterAxisColor)
+			} else {
+				img.Set(x, y, ScatterBackground)
+			}
+		}
+	}
+
+	for i, set := range sets {
+		for _, point := range set {
+			x := int((point[0]/scale + 1) / 2 * float64(size))
+			y := int((1 - point[1]/scale) / 2 * float64(size))
+			for dy := -1; dy <= 1; dy++ {
+				for dx := -1; dx <= 1; dx++ {
+					px, py := x+dx, y+dy
+					if px >= 0 && py >= 0 && px < size && py < size {
+						img.Set(px, py, colors[i])
+					}
+				}
+			}
+		}
+	}
+	return img
+}
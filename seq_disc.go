@@ -0,0 +1,202 @@
+package gans
+
+import (
+	"errors"
+
+	"github.com/unixpickle/autofunc"
+	"github.com/unixpickle/autofunc/seqfunc"
+	"github.com/unixpickle/num-analysis/linalg"
+	"github.com/unixpickle/serializer"
+	"github.com/unixpickle/weakai/neuralnet"
+	"github.com/unixpickle/weakai/rnn"
+)
+
+func init() {
+	var c ConvDisc
+	serializer.RegisterTypedDeserializer(c.SerializerType(), DeserializeConvDisc)
+}
+
+// NewBidirectionalDisc creates a discriminator which runs
+// an LSTM over a sequence in both directions, so that
+// every output depends on the entire sequence.
+//
+// It produces one output per timestep, and is meant to be
+// used with a sequence-level DiscMode.
+func NewBidirectionalDisc(inSize, hiddenSize int) *rnn.Bidirectional {
+	return &rnn.Bidirectional{
+		Forward:  &rnn.BlockSeqFunc{B: rnn.NewLSTM(inSize, hiddenSize)},
+		Backward: &rnn.BlockSeqFunc{B: rnn.NewLSTM(inSize, hiddenSize)},
+		Output: &rnn.NetworkSeqFunc{
+			Network: neuralnet.Network{
+				neuralnet.NewDenseLayer(hiddenSize*2, 1),
+			},
+		},
+	}
+}
+
+// ConvDisc is a one-dimensional convolutional
+// discriminator.
+//
+// At every timestep, the last Width input vectors
+// (padded with zeros at the start of the sequence) are
+// concatenated and fed to Network.
+// Thus, each output only depends on the current and past
+// inputs.
+type ConvDisc struct {
+	Width   int
+	Network neuralnet.Network
+}
+
+// NewConvDisc creates a ConvDisc with one convolutional
+// layer of the given number of filters, followed by a
+// ReLU and a dense output layer.
+func NewConvDisc(inSize, width, filters int) *ConvDisc {
+	return &ConvDisc{
+		Width: width,
+		Network: neuralnet.Network{
+			neuralnet.NewDenseLayer(inSize*width, filters),
+			neuralnet.ReLU{},
+			neuralnet.NewDenseLayer(filters, 1),
+		},
+	}
+}
+
+// DeserializeConvDisc deserializes a ConvDisc.
+func DeserializeConvDisc(d []byte) (*ConvDisc, error) {
+	slice, err := serializer.DeserializeSlice(d)
+	if err != nil {
+		return nil, err
+	}
+	if len(slice) != 2 {
+		return nil, errors.New("invalid ConvDisc slice")
+	}
+	width, ok1 := slice[0].(serializer.Int)
+	network, ok2 := slice[1].(neuralnet.Network)
+	if !ok1 || !ok2 {
+		return nil, errors.New("invalid ConvDisc slice")
+	}
+	return &ConvDisc{Width: int(width), Network: network}, nil
+}
+
+// ApplySeqs applies the discriminator to sequences.
+func (c *ConvDisc) ApplySeqs(in seqfunc.Result) seqfunc.Result {
+	windows := &windowSeqs{
+		Input:  in,
+		Width:  c.Width,
+		Output: slidingWindows(in.OutputSeqs(), c.Width),
+	}
+	return (&rnn.NetworkSeqFunc{Network: c.Network}).ApplySeqs(windows)
+}
+
+// ApplySeqsR applies the discriminator to sequences.
+func (c *ConvDisc) ApplySeqsR(rv autofunc.RVector, in seqfunc.RResult) seqfunc.RResult {
+	windows := &windowRSeqs{
+		Input:   in,
+		Width:   c.Width,
+		Output:  slidingWindows(in.OutputSeqs(), c.Width),
+		ROutput: slidingWindows(in.ROutputSeqs(), c.Width),
+	}
+	return (&rnn.NetworkSeqFunc{Network: c.Network}).ApplySeqsR(rv, windows)
+}
+
+// Parameters returns the parameters of the network.
+func (c *ConvDisc) Parameters() []*autofunc.Variable {
+	return c.Network.Parameters()
+}
+
+// SerializerType returns the unique ID used to serialize
+// a ConvDisc with the serializer package.
+func (c *ConvDisc) SerializerType() string {
+	return "github.com/unixpickle/gans.ConvDisc"
+}
+
+// Serialize serializes the discriminator.
+func (c *ConvDisc) Serialize() ([]byte, error) {
+	return serializer.SerializeSlice([]serializer.Serializer{
+		serializer.Int(c.Width),
+		c.Network,
+	})
+}
+
+type windowSeqs struct {
+	Input  seqfunc.Result
+	Width  int
+	Output [][]linalg.Vector
+}
+
+func (w *windowSeqs) OutputSeqs() [][]linalg.Vector {
+	return w.Output
+}
+
+func (w *windowSeqs) PropagateGradient(upstream [][]linalg.Vector, grad autofunc.Gradient) {
+	w.Input.PropagateGradient(unslideWindows(upstream, w.Input.OutputSeqs(), w.Width), grad)
+}
+
+type windowRSeqs struct {
+	Input   seqfunc.RResult
+	Width   int
+	Output  [][]linalg.Vector
+	ROutput [][]linalg.Vector
+}
+
+func (w *windowRSeqs) OutputSeqs() [][]linalg.Vector {
+	return w.Output
+}
+
+func (w *windowRSeqs) ROutputSeqs() [][]linalg.Vector {
+	return w.ROutput
+}
+
+func (w *windowRSeqs) PropagateRGradient(upstream, upstreamR [][]linalg.Vector,
+	rgrad autofunc.RGradient, grad autofunc.Gradient) {
+	inSeqs := w.Input.OutputSeqs()
+	w.Input.PropagateRGradient(unslideWindows(upstream, inSeqs, w.Width),
+		unslideWindows(upstreamR, inSeqs, w.Width), rgrad, grad)
+}
+
+// slidingWindows concatenates the last width vectors at
+// every timestep, padding with zeros where needed.
+func slidingWindows(seqs [][]linalg.Vector, width int) [][]linalg.Vector {
+	res := make([][]linalg.Vector, len(seqs))
+	for i, seq := range seqs {
+		res[i] = make([]linalg.Vector, len(seq))
+		for t, vec := range seq {
+			window := make(linalg.Vector, 0, len(vec)*width)
+			for j := t - width + 1; j <= t; j++ {
+				if j < 0 {
+					window = append(window, make(linalg.Vector, len(vec))...)
+				} else {
+					window = append(window, seq[j]...)
+				}
+			}
+			res[i][t] = window
+		}
+	}
+	return res
+}
+
+// unslideWindows is the transpose of slidingWindows,
+// mapping gradients for the windows to gradients for the
+// inputs.
+func unslideWindows(upstream, inSeqs [][]linalg.Vector, width int) [][]linalg.Vector {
+	res := make([][]linalg.Vector, len(inSeqs))
+	for i, seq := range inSeqs {
+		res[i] = make([]linalg.Vector, len(seq))
+		for t, vec := range seq {
+			res[i][t] = make(linalg.Vector, len(vec))
+		}
+		for t, window := range upstream[i] {
+			for k := 0; k < width; k++ {
+				j := t - width + 1 + k
+				if j < 0 {
+					continue
+				}
+				size := len(res[i][j])
+				for l, x := range window[k*size : (k+1)*size] {
+					res[i][j][l] += x
+				}
+			}
+		}
+	}
+	return res
+}
@@ -0,0 +1,73 @@
+package gans
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/unixpickle/num-analysis/linalg"
+)
+
+func TestSlidingWindowsTranspose(t *testing.T) {
+	const vecSize = 3
+	for _, width := range []int{1, 2, 4} {
+		inSeqs := randomSeqs([]int{5, 1, 0, 3}, vecSize)
+		windows := slidingWindows(inSeqs, width)
+		upstream := randomSeqs([]int{5, 1, 0, 3}, vecSize*width)
+		downstream := unslideWindows(upstream, inSeqs, width)
+
+		// <W*x, y> must equal <x, W^T*y>.
+		expected := seqsDot(windows, upstream)
+		actual := seqsDot(inSeqs, downstream)
+		if math.Abs(expected-actual) > 1e-8 {
+			t.Errorf("width %d: expected %f but got %f", width, expected, actual)
+		}
+	}
+}
+
+func TestSlidingWindowsPadding(t *testing.T) {
+	seqs := [][]linalg.Vector{{{1}, {2}, {3}}}
+	windows := slidingWindows(seqs, 2)
+	expected := []linalg.Vector{{0, 1}, {1, 2}, {2, 3}}
+	for i, vec := range windows[0] {
+		if !vecsEqual(vec, expected[i]) {
+			t.Errorf("timestep %d: expected %v but got %v", i, expected[i], vec)
+		}
+	}
+}
+
+func randomSeqs(lengths []int, vecSize int) [][]linalg.Vector {
+	res := make([][]linalg.Vector, len(lengths))
+	for i, length := range lengths {
+		res[i] = make([]linalg.Vector, length)
+		for j := range res[i] {
+			res[i][j] = make(linalg.Vector, vecSize)
+			for k := range res[i][j] {
+				res[i][j][k] = rand.NormFloat64()
+			}
+		}
+	}
+	return res
+}
+
+func seqsDot(s1, s2 [][]linalg.Vector) float64 {
+	var res float64
+	for i, seq := range s1 {
+		for j, vec := range seq {
+			res += vec.Dot(s2[i][j])
+		}
+	}
+	return res
+}
+
+func vecsEqual(v1, v2 linalg.Vector) bool {
+	if len(v1) != len(v2) {
+		return false
+	}
+	for i, x := range v1 {
+		if math.Abs(x-v2[i]) > 1e-8 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,285 @@
+package gans
+
+import (
+	"math"
+	"math/rand"
+
+	"github.com/unixpickle/autofunc"
+	"github.com/unixpickle/num-analysis/linalg"
+	"github.com/unixpickle/serializer"
+	"github.com/unixpickle/sgd"
+	"github.com/unixpickle/weakai/neuralnet"
+)
+
+func init() {
+	var s SpectralNorm
+	serializer.RegisterTypedDeserializer(s.SerializerType(), DeserializeSpectralNorm)
+}
+
+// SpectralNorm wraps a *neuralnet.DenseLayer or a
+// *neuralnet.ConvLayer and divides its weights by an
+// estimate of their largest singular value.
+//
+// The estimate is refined by one step of power iteration
+// every time a gradient is propagated into the layer's
+// weights, i.e. during training.
+// Applying the layer for inference (e.g. for evaluation or
+// saliency maps) leaves the estimate unchanged.
+// Gradients are propagated through the singular value,
+// treating the singular vectors as constants.
+type SpectralNorm struct {
+	// Layer is the wrapped layer.
+	Layer neuralnet.Layer
+
+	// U and V are the current estimates of the left and
+	// right singular vectors of the weight matrix.
+	// They are initialized randomly if they are empty.
+	U linalg.Vector
+	V linalg.Vector
+}
+
+// NewSpectralNorm wraps a dense or convolutional layer.
+func NewSpectralNorm(layer neuralnet.Layer) *SpectralNorm {
+	switch layer.(type) {
+	case *neuralnet.DenseLayer, *neuralnet.ConvLayer:
+	default:
+		panic("unsupported layer for spectral normalization")
+	}
+	return &SpectralNorm{Layer: layer}
+}
+
+// DeserializeSpectralNorm deserializes a SpectralNorm.
+func DeserializeSpectralNorm(d []byte) (*SpectralNorm, error) {
+	var res SpectralNorm
+	var u, v serializer.Float64Slice
+	if err := serializer.DeserializeAny(d, &res.Layer, &u, &v); err != nil {
+		return nil, err
+	}
+	res.U = linalg.Vector(u)
+	res.V = linalg.Vector(v)
+	return &res, nil
+}
+
+// Apply applies the normalized layer.
+func (s *SpectralNorm) Apply(in autofunc.Result) autofunc.Result {
+	return s.normalize(s.Layer.Apply(in), 1)
+}
+
+// ApplyR applies the normalized layer.
+func (s *SpectralNorm) ApplyR(rv autofunc.RVector, in autofunc.RResult) autofunc.RResult {
+	return s.normalizeR(rv, s.Layer.ApplyR(rv, in), 1)
+}
+
+// Batch applies the normalized layer in batch.
+func (s *SpectralNorm) Batch(in autofunc.Result, n int) autofunc.Result {
+	var out autofunc.Result
+	if b, ok := s.Layer.(autofunc.Batcher); ok {
+		out = b.Batch(in, n)
+	} else {
+		inSize := len(in.Output()) / n
+		outs := make([]autofunc.Result, n)
+		for i := range outs {
+			outs[i] = s.Layer.Apply(autofunc.Slice(in, i*inSize, (i+1)*inSize))
+		}
+		out = autofunc.Concat(outs...)
+	}
+	return s.normalize(out, n)
+}
+
+// BatchR applies the normalized layer in batch.
+func (s *SpectralNorm) BatchR(rv autofunc.RVector, in autofunc.RResult,
+	n int) autofunc.RResult {
+	var out autofunc.RResult
+	if b, ok := s.Layer.(autofunc.RBatcher); ok {
+		out = b.BatchR(rv, in, n)
+	} else {
+		inSize := len(in.Output()) / n
+		outs := make([]autofunc.RResult, n)
+		for i := range outs {
+			outs[i] = s.Layer.ApplyR(rv, autofunc.SliceR(in, i*inSize, (i+1)*inSize))
+		}
+		out = autofunc.ConcatR(outs...)
+	}
+	return s.normalizeR(rv, out, n)
+}
+
+// Parameters returns the parameters of the wrapped layer.
+func (s *SpectralNorm) Parameters() []*autofunc.Variable {
+	if l, ok := s.Layer.(sgd.Learner); ok {
+		return l.Parameters()
+	}
+	return nil
+}
+
+// Randomize randomizes the wrapped layer and resets the
+// singular vector estimates.
+func (s *SpectralNorm) Randomize() {
+	if r, ok := s.Layer.(interface {
+		Randomize()
+	}); ok {
+		r.Randomize()
+	}
+	s.U = nil
+	s.V = nil
+}
+
+// SerializerType returns the unique ID used to serialize
+// a SpectralNorm with the serializer package.
+func (s *SpectralNorm) SerializerType() string {
+	return "github.com/unixpickle/gans.SpectralNorm"
+}
+
+// Serialize serializes the layer and its singular vector
+// estimates.
+func (s *SpectralNorm) Serialize() ([]byte, error) {
+	return serializer.SerializeAny(s.Layer, serializer.Float64Slice(s.U),
+		serializer.Float64Slice(s.V))
+}
+
+func (s *SpectralNorm) normalize(out autofunc.Result, n int) autofunc.Result {
+	weightVars, bias, biasCount := s.parameterVars()
+	weights := make([]autofunc.Result, len(weightVars))
+	for i, w := range weightVars {
+		weights[i] = w
+	}
+	uv := &autofunc.Variable{Vector: s.outerUV()}
+	sigma := autofunc.SumAll(autofunc.Mul(autofunc.Concat(weights...), uv))
+	biases := repeatResult(bias, biasCount*n)
+	linear := autofunc.Sub(out, biases)
+	res := autofunc.Add(autofunc.ScaleFirst(linear, autofunc.Inverse(sigma)), biases)
+	return &powerIterResult{Result: res, Layer: s, Weights: weightVars[0]}
+}
+
+func (s *SpectralNorm) normalizeR(rv autofunc.RVector, out autofunc.RResult,
+	n int) autofunc.RResult {
+	weightVars, bias, biasCount := s.parameterVars()
+	weights := make([]autofunc.RResult, len(weightVars))
+	for i, w := range weightVars {
+		weights[i] = autofunc.NewRVariable(w, rv)
+	}
+	uv := autofunc.NewRVariable(&autofunc.Variable{Vector: s.outerUV()}, rv)
+	sigma := autofunc.SumAllR(autofunc.MulR(autofunc.ConcatR(weights...), uv))
+	biasR := autofunc.NewRVariable(bias, rv)
+	biasRs := make([]autofunc.RResult, biasCount*n)
+	for i := range biasRs {
+		biasRs[i] = biasR
+	}
+	biases := autofunc.ConcatR(biasRs...)
+	linear := autofunc.SubR(out, biases)
+	return autofunc.AddR(autofunc.ScaleFirstR(linear, autofunc.InverseR(sigma)), biases)
+}
+
+// parameterVars returns the variables which make up the
+// rows of the weight matrix, the bias variable, and the
+// number of times the bias is repeated in the output.
+func (s *SpectralNorm) parameterVars() ([]*autofunc.Variable, *autofunc.Variable, int) {
+	switch layer := s.Layer.(type) {
+	case *neuralnet.DenseLayer:
+		return []*autofunc.Variable{layer.Weights.Data}, layer.Biases.Var, 1
+	case *neuralnet.ConvLayer:
+		count := layer.OutputWidth() * layer.OutputHeight()
+		return layer.FilterVars, layer.Biases, count
+	default:
+		panic("unsupported layer for spectral normalization")
+	}
+}
+
+// weightRows returns the rows of the weight matrix.
+func (s *SpectralNorm) weightRows() []linalg.Vector {
+	switch layer := s.Layer.(type) {
+	case *neuralnet.DenseLayer:
+		data := layer.Weights.Data.Vector
+		rows := make([]linalg.Vector, layer.OutputCount)
+		for i := range rows {
+			rows[i] = data[i*layer.InputCount : (i+1)*layer.InputCount]
+		}
+		return rows
+	case *neuralnet.ConvLayer:
+		rows := make([]linalg.Vector, len(layer.FilterVars))
+		for i, v := range layer.FilterVars {
+			rows[i] = v.Vector
+		}
+		return rows
+	default:
+		panic("unsupported layer for spectral normalization")
+	}
+}
+
+func (s *SpectralNorm) powerIterate() {
+	rows := s.weightRows()
+	if len(s.U) != len(rows) {
+		s.U = randomUnitVector(len(rows))
+	}
+
+	v := make(linalg.Vector, len(rows[0]))
+	for i, row := range rows {
+		for j, x := range row {
+			v[j] += s.U[i] * x
+		}
+	}
+	normalizeVector(v)
+
+	u := make(linalg.Vector, len(rows))
+	for i, row := range rows {
+		u[i] = row.Dot(v)
+	}
+	normalizeVector(u)
+
+	s.U = u
+	s.V = v
+}
+
+// powerIterResult refines the estimate of a SpectralNorm
+// after a gradient is propagated into its weights.
+type powerIterResult struct {
+	autofunc.Result
+
+	Layer   *SpectralNorm
+	Weights *autofunc.Variable
+}
+
+func (p *powerIterResult) PropagateGradient(upstream linalg.Vector, grad autofunc.Gradient) {
+	p.Result.PropagateGradient(upstream, grad)
+	if _, ok := grad[p.Weights]; ok {
+		p.Layer.powerIterate()
+	}
+}
+
+// outerUV computes u*v^T as a row-major vector.
+func (s *SpectralNorm) outerUV() linalg.Vector {
+	if len(s.U) == 0 || len(s.V) == 0 {
+		s.powerIterate()
+	}
+	res := make(linalg.Vector, 0, len(s.U)*len(s.V))
+	for _, x := range s.U {
+		for _, y := range s.V {
+			res = append(res, x*y)
+		}
+	}
+	return res
+}
+
+func repeatResult(r autofunc.Result, n int) autofunc.Result {
+	results := make([]autofunc.Result, n)
+	for i := range results {
+		results[i] = r
+	}
+	return autofunc.Concat(results...)
+}
+
+func randomUnitVector(size int) linalg.Vector {
+	res := make(linalg.Vector, size)
+	for i := range res {
+		res[i] = rand.NormFloat64()
+	}
+	normalizeVector(res)
+	return res
+}
+
+func normalizeVector(v linalg.Vector) {
+	norm := math.Sqrt(v.Dot(v))
+	if norm == 0 {
+		return
+	}
+	v.Scale(1 / norm)
+}
@@ -0,0 +1,98 @@
+package gans
+
+import (
+	"math"
+	"testing"
+
+	"github.com/unixpickle/autofunc"
+	"github.com/unixpickle/num-analysis/linalg"
+	"github.com/unixpickle/weakai/neuralnet"
+)
+
+func TestSpectralNormConverges(t *testing.T) {
+	layer := neuralnet.NewDenseLayer(5, 4)
+	s := NewSpectralNorm(layer)
+	for i := 0; i < 200; i++ {
+		s.powerIterate()
+	}
+	rows := s.weightRows()
+	sigma := s.outerUV().Dot(layer.Weights.Data.Vector)
+	normalized := make([]linalg.Vector, len(rows))
+	for i, row := range rows {
+		normalized[i] = append(linalg.Vector{}, row...)
+		normalized[i].Scale(1 / sigma)
+	}
+	if actual := topSingularValue(normalized); math.Abs(actual-1) > 1e-4 {
+		t.Errorf("expected top singular value 1 but got %f", actual)
+	}
+}
+
+func TestSpectralNormInference(t *testing.T) {
+	s := NewSpectralNorm(neuralnet.NewDenseLayer(3, 2))
+	input := &autofunc.Variable{Vector: randomVec(3)}
+	s.Apply(input)
+	u := append(linalg.Vector{}, s.U...)
+
+	out := s.Apply(input)
+	out.PropagateGradient(randomVec(2), autofunc.NewGradient([]*autofunc.Variable{input}))
+	if !vecsEqual(u, s.U) {
+		t.Error("inference changed the singular vector estimate")
+	}
+
+	out = s.Apply(input)
+	out.PropagateGradient(randomVec(2), autofunc.NewGradient(s.Parameters()))
+	if vecsEqual(u, s.U) {
+		t.Error("training did not refine the singular vector estimate")
+	}
+}
+
+func TestSpectralNormGradient(t *testing.T) {
+	layer := neuralnet.NewDenseLayer(3, 2)
+	s := NewSpectralNorm(layer)
+	s.powerIterate()
+	input := &autofunc.Variable{Vector: randomVec(3)}
+	upstream := randomVec(2)
+	weights := layer.Weights.Data
+
+	// Compute the numerical gradient first, since the
+	// backward pass refines the singular vectors.
+	const epsilon = 1e-5
+	expected := make(linalg.Vector, len(weights.Vector))
+	for i := range weights.Vector {
+		old := weights.Vector[i]
+		weights.Vector[i] = old + epsilon
+		plus := s.Apply(input).Output().Dot(upstream)
+		weights.Vector[i] = old - epsilon
+		minus := s.Apply(input).Output().Dot(upstream)
+		weights.Vector[i] = old
+		expected[i] = (plus - minus) / (2 * epsilon)
+	}
+
+	grad := autofunc.NewGradient(s.Parameters())
+	s.Apply(input).PropagateGradient(upstream, grad)
+	for i, x := range grad[weights] {
+		if math.Abs(x-expected[i]) > 1e-4 {
+			t.Errorf("weight %d: expected gradient %f but got %f", i, expected[i], x)
+		}
+	}
+}
+
+func topSingularValue(rows []linalg.Vector) float64 {
+	v := randomUnitVector(len(rows[0]))
+	var sigma float64
+	for i := 0; i < 1000; i++ {
+		u := make(linalg.Vector, len(rows))
+		for j, row := range rows {
+			u[j] = row.Dot(v)
+		}
+		sigma = math.Sqrt(u.Dot(u))
+		v = make(linalg.Vector, len(rows[0]))
+		for j, row := range rows {
+			for k, x := range row {
+				v[k] += u[j] * x
+			}
+		}
+		normalizeVector(v)
+	}
+	return sigma
+}
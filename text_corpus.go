@@ -0,0 +1,266 @@
+package gans
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"strings"
+	"unicode"
+
+	"github.com/unixpickle/sgd"
+	"github.com/unixpickle/weakai/rnn/seqtoseq"
+)
+
+// CorpusOptions controls how a TextCorpus splits and
+// filters text.
+type CorpusOptions struct {
+	// Terminators contains the characters which end a
+	// sentence.
+	// Terminators are kept at the end of sentences.
+	Terminators string
+
+	// Abbreviations contains words which do not end a
+	// sentence when they are followed by a period.
+	// Other terminators always end a sentence.
+	Abbreviations []string
+
+	// Lines, if true, makes every line a sentence.
+	// Terminators can still split lines further.
+	Lines bool
+
+	// MinLength and MaxLength bound the number of symbols
+	// in a sentence.
+	// A MaxLength of 0 means there is no upper bound.
+	MinLength int
+	MaxLength int
+
+	// Filter, if non-nil, decides whether or not to keep
+	// a sentence.
+	Filter func(sentence string) bool
+}
+
+// DefaultCorpusOptions returns the options used by
+// ReadTextCorpus when none are specified.
+func DefaultCorpusOptions() *CorpusOptions {
+	return &CorpusOptions{
+		Terminators:   ".!?",
+		Abbreviations: []string{"Mr", "Mrs", "Ms", "Dr"},
+		MinLength:     1,
+	}
+}
+
+// TextCorpus is an sgd.SampleSet of sentences.
+//
+// Each sample is a seqtoseq.Sample whose inputs are the
+// one-hot vectors for the sentence's symbols.
+//
+// To use a vocabulary which is learned from the corpus
+// itself, such as a WordVocab or BPEVocab, read the
+// corpus with a vocabulary that accepts all text (e.g.
+// ByteVocab) and no length bounds, then learn the new
+// vocabulary and pass it to SetVocab.
+type TextCorpus struct {
+	Sentences []string
+	Vocab     Vocabulary
+}
+
+// ReadTextCorpus reads the sentences from a text file.
+// If opts is nil, DefaultCorpusOptions() is used.
+func ReadTextCorpus(path string, vocab Vocabulary, opts *CorpusOptions) (*TextCorpus, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return NewTextCorpus(f, vocab, opts)
+}
+
+// NewTextCorpus reads the sentences from a stream of
+// text.
+// Sentences are stored with all runs of whitespace
+// replaced by single spaces.
+// Sentences which the vocabulary cannot encode are
+// dropped.
+// If opts is nil, DefaultCorpusOptions() is used.
+func NewTextCorpus(r io.Reader, vocab Vocabulary, opts *CorpusOptions) (*TextCorpus, error) {
+	if opts == nil {
+		opts = DefaultCorpusOptions()
+	}
+	res := &TextCorpus{Vocab: vocab}
+	var sentence []rune
+	finish := func() {
+		text := strings.Join(strings.Fields(string(sentence)), " ")
+		sentence = sentence[:0]
+		if opts.Filter != nil && !opts.Filter(text) {
+			return
+		}
+		if opts.accepts(vocab, text) {
+			res.Sentences = append(res.Sentences, text)
+		}
+	}
+
+	reader := bufio.NewReader(r)
+	for {
+		ch, _, err := reader.ReadRune()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		if ch == '\n' && opts.Lines {
+			finish()
+			continue
+		}
+		if strings.ContainsRune(opts.Terminators, ch) {
+			if ch != '.' || !endsWithAbbreviation(sentence, opts.Abbreviations) {
+				sentence = append(sentence, ch)
+				finish()
+				continue
+			}
+		}
+		sentence = append(sentence, ch)
+	}
+	if strings.TrimSpace(string(sentence)) != "" {
+		finish()
+	}
+	return res, nil
+}
+
+// SetVocab replaces the corpus's vocabulary and drops
+// the sentences which the new vocabulary cannot encode or
+// which do not satisfy the length bounds of opts, as
+// measured in the new vocabulary's symbols.
+// The other fields of opts are ignored.
+// If opts is nil, DefaultCorpusOptions() is used.
+func (t *TextCorpus) SetVocab(vocab Vocabulary, opts *CorpusOptions) {
+	if opts == nil {
+		opts = DefaultCorpusOptions()
+	}
+	var kept []string
+	for _, sentence := range t.Sentences {
+		if opts.accepts(vocab, sentence) {
+			kept = append(kept, sentence)
+		}
+	}
+	t.Sentences = kept
+	t.Vocab = vocab
+}
+
+// HoldOut removes the last n sentences from the corpus
+// and returns them, e.g. for use as references when
+// evaluating generated text.
+func (t *TextCorpus) HoldOut(n int) []string {
+	if n > len(t.Sentences) {
+		n = len(t.Sentences)
+	}
+	idx := len(t.Sentences) - n
+	res := t.Sentences[idx:]
+	t.Sentences = t.Sentences[:idx]
+	return res
+}
+
+// Buckets splits the corpus into sub-corpora by sentence
+// length, so that batches can be drawn from sentences of
+// similar lengths.
+// Sentences with at most maxLens[0] symbols go into the
+// first bucket, then sentences with at most maxLens[1]
+// symbols into the second, and so on.
+// Longer sentences go into one final bucket.
+// Empty buckets are omitted.
+func (t *TextCorpus) Buckets(maxLens ...int) []*TextCorpus {
+	buckets := make([]*TextCorpus, len(maxLens)+1)
+	for i := range buckets {
+		buckets[i] = &TextCorpus{Vocab: t.Vocab}
+	}
+	for _, sentence := range t.Sentences {
+		symbols, _ := t.Vocab.Encode(sentence)
+		idx := len(maxLens)
+		for i, maxLen := range maxLens {
+			if len(symbols) <= maxLen {
+				idx = i
+				break
+			}
+		}
+		buckets[idx].Sentences = append(buckets[idx].Sentences, sentence)
+	}
+	var res []*TextCorpus
+	for _, bucket := range buckets {
+		if len(bucket.Sentences) > 0 {
+			res = append(res, bucket)
+		}
+	}
+	return res
+}
+
+// Len returns the number of sentences.
+func (t *TextCorpus) Len() int {
+	return len(t.Sentences)
+}
+
+// Swap swaps two sentences.
+func (t *TextCorpus) Swap(i, j int) {
+	t.Sentences[i], t.Sentences[j] = t.Sentences[j], t.Sentences[i]
+}
+
+// GetSample returns a seqtoseq.Sample for a sentence.
+func (t *TextCorpus) GetSample(i int) interface{} {
+	symbols, _ := t.Vocab.Encode(t.Sentences[i])
+	return seqtoseq.Sample{Inputs: OneHotSeq(symbols, t.Vocab.Size())}
+}
+
+// Copy creates a copy of the corpus.
+func (t *TextCorpus) Copy() sgd.SampleSet {
+	return &TextCorpus{
+		Sentences: append([]string{}, t.Sentences...),
+		Vocab:     t.Vocab,
+	}
+}
+
+// Subset returns a subset of the corpus.
+func (t *TextCorpus) Subset(start, end int) sgd.SampleSet {
+	return &TextCorpus{
+		Sentences: t.Sentences[start:end],
+		Vocab:     t.Vocab,
+	}
+}
+
+// LettersAndSpaces is a sentence filter which only
+// accepts sentences made of ASCII letters and spaces,
+// aside from a final '.', '!', or '?'.
+func LettersAndSpaces(sentence string) bool {
+	if strings.HasSuffix(sentence, ".") || strings.HasSuffix(sentence, "!") ||
+		strings.HasSuffix(sentence, "?") {
+		sentence = sentence[:len(sentence)-1]
+	}
+	if len(sentence) == 0 {
+		return false
+	}
+	for _, ch := range sentence {
+		if !(ch >= 'a' && ch <= 'z') && !(ch >= 'A' && ch <= 'Z') && ch != ' ' {
+			return false
+		}
+	}
+	return true
+}
+
+// accepts checks if a vocabulary can encode a sentence
+// within the length bounds.
+func (c *CorpusOptions) accepts(vocab Vocabulary, sentence string) bool {
+	symbols, ok := vocab.Encode(sentence)
+	return ok && len(symbols) >= c.MinLength &&
+		(c.MaxLength == 0 || len(symbols) <= c.MaxLength)
+}
+
+func endsWithAbbreviation(sentence []rune, abbreviations []string) bool {
+	text := string(sentence)
+	for _, abbr := range abbreviations {
+		if !strings.HasSuffix(text, abbr) {
+			continue
+		}
+		prefix := []rune(text[:len(text)-len(abbr)])
+		if len(prefix) == 0 || !unicode.IsLetter(prefix[len(prefix)-1]) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,20 @@
+package gans
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTextCorpusSentences(t *testing.T) {
+	input := "I met Mr. Smith. Hi Mr! Who is Mr? Then  it\nended"
+	corpus, err := NewTextCorpus(strings.NewReader(input), ByteVocab{},
+		DefaultCorpusOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"I met Mr. Smith.", "Hi Mr!", "Who is Mr?", "Then it ended"}
+	if !reflect.DeepEqual(corpus.Sentences, expected) {
+		t.Errorf("expected %q but got %q", expected, corpus.Sentences)
+	}
+}
@@ -0,0 +1,136 @@
+package gans
+
+import (
+	"fmt"
+	"html"
+	"image"
+	"image/color"
+	"image/draw"
+	"io"
+
+	"golang.org/x/image/font/basicfont"
+)
+
+var TextSheetBackground = color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}
+
+// A TextSample is a generated sequence to display on a
+// contact sheet.
+type TextSample struct {
+	// Symbols contains the index of each symbol.
+	Symbols []int
+
+	// Scores, if non-nil, contains a score between 0 and 1
+	// for every symbol, such as the probability that a
+	// discriminator assigns to the sequence being real at
+	// that timestep.
+	// Scores are displayed as a heatmap from red (0) to
+	// green (1).
+	// Symbols past the end of Scores are displayed without
+	// a score.
+	Scores []float64
+}
+
+// ByteDecoder decodes a symbol as a single byte, which
+// is interpreted as a Latin-1 character.
+func ByteDecoder(symbol int) string {
+	return string(rune(byte(symbol)))
+}
+
+// TextSheetHTML writes an HTML contact sheet of the
+// samples, decoding each symbol with decode.
+func TextSheetHTML(w io.Writer, samples []TextSample, decode func(int) string) error {
+	_, err := io.WriteString(w, "<!doctype html>\n<html><head><meta charset=\"utf-8\">"+
+		"<style>div.sample{font-family:monospace;white-space:pre-wrap;"+
+		"margin-bottom:1em;}</style></head><body>\n")
+	if err != nil {
+		return err
+	}
+	for _, sample := range samples {
+		if _, err := io.WriteString(w, "<div class=\"sample\">"); err != nil {
+			return err
+		}
+		for i, symbol := range sample.Symbols {
+			text := html.EscapeString(decode(symbol))
+			if i >= len(sample.Scores) {
+				_, err = io.WriteString(w, text)
+			} else {
+				c := heatColor(sample.Scores[i])
+				_, err = fmt.Fprintf(w, "<span style=\"background:#%02x%02x%02x\" "+
+					"title=\"%.3f\">%s</span>", c.R, c.G, c.B, sample.Scores[i], text)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		if _, err := io.WriteString(w, "</div>\n"); err != nil {
+			return err
+		}
+	}
+	_, err = io.WriteString(w, "</body></html>\n")
+	return err
+}
+
+// TextSheetImage renders a contact sheet of the samples,
+// decoding each symbol with decode.
+// Lines are wrapped so that the image is at most width
+// pixels wide, and samples are separated by blank lines.
+func TextSheetImage(samples []TextSample, decode func(int) string, width int) image.Image {
+	const margin = 4
+	lineHeight := basicfont.Face7x13.Height
+
+	type placedText struct {
+		text  string
+		x, y  int
+		color color.RGBA
+		score bool
+	}
+	var placed []placedText
+	y := margin
+	for _, sample := range samples {
+		x := margin
+		for i, symbol := range sample.Symbols {
+			text := decode(symbol)
+			w := textWidth(text)
+			if x+w > width-margin && x > margin {
+				x = margin
+				y += lineHeight
+			}
+			p := placedText{text: text, x: x, y: y}
+			if i < len(sample.Scores) {
+				p.color = heatColor(sample.Scores[i])
+				p.score = true
+			}
+			placed = append(placed, p)
+			x += w
+		}
+		y += lineHeight * 2
+	}
+
+	height := maxInt(y+margin-lineHeight, margin*2)
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(img, img.Bounds(), image.NewUniform(TextSheetBackground), image.ZP, draw.Src)
+	for _, p := range placed {
+		if p.score {
+			rect := image.Rect(p.x, p.y, p.x+textWidth(p.text), p.y+lineHeight)
+			draw.Draw(img, rect, image.NewUniform(p.color), image.ZP, draw.Src)
+		}
+		drawText(img, p.text, color.Black, p.x, p.y)
+	}
+	return img
+}
+
+// heatColor maps a score from 0 to 1 to a light color
+// between red and green.
+func heatColor(score float64) color.RGBA {
+	if score < 0 {
+		score = 0
+	} else if score > 1 {
+		score = 1
+	}
+	return color.RGBA{
+		R: uint8(0xff - 0x80*score + 0.5),
+		G: uint8(0x7f + 0x80*score + 0.5),
+		B: 0x7f,
+		A: 0xff,
+	}
+}
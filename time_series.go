@@ -0,0 +1,240 @@
+package gans
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/unixpickle/num-analysis/linalg"
+	"github.com/unixpickle/sgd"
+	"github.com/unixpickle/weakai/rnn/seqtoseq"
+)
+
+// CSVOptions controls how a SeriesSet is read from a CSV
+// file.
+type CSVOptions struct {
+	// Header indicates that the first row contains column
+	// names rather than data.
+	Header bool
+
+	// Columns lists the indices of the columns to use.
+	// If it is nil, every column is used.
+	Columns []int
+
+	// Window is the length of each sample.
+	// If it is 0, the entire series is one sample.
+	// It is an error for Window to exceed the number of
+	// rows in the series.
+	Window int
+
+	// Stride is the number of rows between the starts of
+	// consecutive windows.
+	// A value of 0 is treated as 1.
+	Stride int
+
+	// Normalize indicates that every column should be
+	// shifted and scaled to have mean 0 and variance 1.
+	Normalize bool
+}
+
+// SeriesSet is an sgd.SampleSet of windows from a
+// multivariate time series.
+//
+// Each sample is a seqtoseq.Sample whose inputs are the
+// rows of a window.
+type SeriesSet struct {
+	// Rows contains the (normalized) rows of the series.
+	Rows []linalg.Vector
+
+	// Starts contains the index of the first row of every
+	// window.
+	Starts []int
+
+	// Window is the number of rows in each window.
+	Window int
+
+	// Mean and Stddev, if non-nil, contain the statistics
+	// which were used to normalize the rows.
+	Mean   linalg.Vector
+	Stddev linalg.Vector
+}
+
+// ReadCSVSeries reads a time series from a CSV file.
+// If opts is nil, all of the columns are used and the
+// entire series is a single sample.
+func ReadCSVSeries(path string, opts *CSVOptions) (*SeriesSet, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return NewCSVSeries(f, opts)
+}
+
+// NewCSVSeries reads a time series from a stream of CSV
+// data.
+// If opts is nil, all of the columns are used and the
+// entire series is a single sample.
+func NewCSVSeries(r io.Reader, opts *CSVOptions) (*SeriesSet, error) {
+	if opts == nil {
+		opts = &CSVOptions{}
+	}
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	res := &SeriesSet{}
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		if line == 1 && opts.Header {
+			continue
+		}
+		row, err := csvRow(record, opts.Columns)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s", line, err)
+		}
+		if len(res.Rows) > 0 && len(row) != len(res.Rows[0]) {
+			return nil, fmt.Errorf("line %d: expected %d values but got %d", line,
+				len(res.Rows[0]), len(row))
+		}
+		res.Rows = append(res.Rows, row)
+	}
+	if len(res.Rows) == 0 {
+		return nil, errors.New("no rows in series")
+	}
+
+	res.Window = opts.Window
+	if res.Window == 0 {
+		res.Window = len(res.Rows)
+	} else if res.Window < 0 {
+		return nil, errors.New("window must not be negative")
+	} else if res.Window > len(res.Rows) {
+		return nil, fmt.Errorf("window of %d rows exceeds series of %d rows",
+			res.Window, len(res.Rows))
+	}
+	stride := opts.Stride
+	if stride == 0 {
+		stride = 1
+	} else if stride < 0 {
+		return nil, errors.New("stride must not be negative")
+	}
+	for i := 0; i+res.Window <= len(res.Rows); i += stride {
+		res.Starts = append(res.Starts, i)
+	}
+
+	if opts.Normalize {
+		res.normalize()
+	}
+	return res, nil
+}
+
+// Len returns the number of windows.
+func (s *SeriesSet) Len() int {
+	return len(s.Starts)
+}
+
+// Swap swaps two windows.
+func (s *SeriesSet) Swap(i, j int) {
+	s.Starts[i], s.Starts[j] = s.Starts[j], s.Starts[i]
+}
+
+// GetSample returns a seqtoseq.Sample for a window.
+func (s *SeriesSet) GetSample(i int) interface{} {
+	start := s.Starts[i]
+	return seqtoseq.Sample{Inputs: s.Rows[start : start+s.Window]}
+}
+
+// Copy creates a copy of the set.
+// The rows are not copied.
+func (s *SeriesSet) Copy() sgd.SampleSet {
+	res := *s
+	res.Starts = append([]int{}, s.Starts...)
+	return &res
+}
+
+// Subset returns a subset of the set.
+func (s *SeriesSet) Subset(start, end int) sgd.SampleSet {
+	res := *s
+	res.Starts = s.Starts[start:end]
+	return &res
+}
+
+// Denormalize undoes normalization on a sequence of
+// vectors, such as one produced by a generator.
+// If the set was not normalized, seq is returned as-is.
+func (s *SeriesSet) Denormalize(seq []linalg.Vector) []linalg.Vector {
+	return DenormalizeSeries(seq, s.Mean, s.Stddev)
+}
+
+// DenormalizeSeries undoes normalization with the given
+// statistics, such as those of a SeriesSet.
+// If mean is nil, seq is returned as-is.
+func DenormalizeSeries(seq []linalg.Vector, mean, stddev linalg.Vector) []linalg.Vector {
+	if mean == nil {
+		return seq
+	}
+	res := make([]linalg.Vector, len(seq))
+	for i, vec := range seq {
+		res[i] = make(linalg.Vector, len(vec))
+		for j, x := range vec {
+			res[i][j] = x*stddev[j] + mean[j]
+		}
+	}
+	return res
+}
+
+func (s *SeriesSet) normalize() {
+	size := len(s.Rows[0])
+	s.Mean = make(linalg.Vector, size)
+	s.Stddev = make(linalg.Vector, size)
+	for _, row := range s.Rows {
+		for i, x := range row {
+			s.Mean[i] += x / float64(len(s.Rows))
+		}
+	}
+	for _, row := range s.Rows {
+		for i, x := range row {
+			s.Stddev[i] += (x - s.Mean[i]) * (x - s.Mean[i])
+		}
+	}
+	for i, x := range s.Stddev {
+		s.Stddev[i] = math.Sqrt(x / float64(len(s.Rows)))
+		if s.Stddev[i] == 0 {
+			s.Stddev[i] = 1
+		}
+	}
+	for _, row := range s.Rows {
+		for i, x := range row {
+			row[i] = (x - s.Mean[i]) / s.Stddev[i]
+		}
+	}
+}
+
+func csvRow(record []string, columns []int) (linalg.Vector, error) {
+	if columns == nil {
+		columns = make([]int, len(record))
+		for i := range columns {
+			columns[i] = i
+		}
+	}
+	res := make(linalg.Vector, len(columns))
+	for i, col := range columns {
+		if col >= len(record) {
+			return nil, fmt.Errorf("missing column %d", col)
+		}
+		val, err := strconv.ParseFloat(strings.TrimSpace(record[col]), 64)
+		if err != nil {
+			return nil, err
+		}
+		res[i] = val
+	}
+	return res, nil
+}
@@ -0,0 +1,27 @@
+package gans
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCSVSeriesWindows(t *testing.T) {
+	csv := "a,b\n1,2\n3,4\n5,6\n7,8\n"
+	opts := &CSVOptions{Header: true, Columns: []int{1}, Window: 2, Stride: 2}
+	series, err := NewCSVSeries(strings.NewReader(csv), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(series.Starts, []int{0, 2}) {
+		t.Errorf("unexpected window starts: %v", series.Starts)
+	}
+	if row := series.Rows[3]; len(row) != 1 || row[0] != 8 {
+		t.Errorf("unexpected last row: %v", row)
+	}
+
+	opts.Window = 5
+	if _, err := NewCSVSeries(strings.NewReader(csv), opts); err == nil {
+		t.Error("expected error for window longer than series")
+	}
+}
@@ -0,0 +1,125 @@
+package gans
+
+import (
+	"fmt"
+	"image"
+	"image/gif"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/unixpickle/num-analysis/linalg"
+	"github.com/unixpickle/weakai/neuralnet"
+)
+
+const timelapseFramePrefix = "frame_"
+
+// Timelapse records GridSample renderings of an FM's
+// generator on a fixed set of latent vectors throughout
+// training.
+//
+// The latent vectors are stored in the model's
+// FixedLatents field so that they are saved with the
+// checkpoint.
+// Frames are written to a directory as numbered PNG
+// files, and numbering resumes after the last existing
+// frame, so resumed runs continue the same timelapse.
+type Timelapse struct {
+	// Dir is the directory to which frames are written.
+	Dir string
+
+	// Interval is the number of iterations between frames.
+	Interval int
+
+	// Rows and Cols specify the layout of each frame.
+	Rows int
+	Cols int
+
+	// Render converts generator outputs to images.
+	Render func(linalg.Vector) *neuralnet.Tensor3
+}
+
+// Step records a frame if iteration is a multiple of
+// t.Interval.
+func (t *Timelapse) Step(f *FM, iteration int) error {
+	if t.Interval == 0 || iteration%t.Interval != 0 {
+		return nil
+	}
+	return t.Record(f)
+}
+
+// Record renders a frame and writes it to the directory.
+func (t *Timelapse) Record(f *FM) error {
+	latents := f.fixedLatents(t.Rows * t.Cols)
+	var idx int
+	img := GridSample(t.Rows, t.Cols, func() *neuralnet.Tensor3 {
+		idx++
+		return t.Render(f.GenerateLatent(latents[idx-1]))
+	})
+
+	if err := os.MkdirAll(t.Dir, 0755); err != nil {
+		return err
+	}
+	frames, err := t.framePaths()
+	if err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%s%06d.png", timelapseFramePrefix, len(frames))
+	outFile, err := os.Create(filepath.Join(t.Dir, name))
+	if err != nil {
+		return err
+	}
+	defer outFile.Close()
+	return png.Encode(outFile, img)
+}
+
+// WriteGIF assembles every frame in the directory into an
+// animated GIF.
+// The delay between frames is measured in 100ths of a
+// second.
+func (t *Timelapse) WriteGIF(path string, delay int) error {
+	paths, err := t.framePaths()
+	if err != nil {
+		return err
+	}
+	var frames []image.Image
+	for _, p := range paths {
+		f, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		img, err := png.Decode(f)
+		f.Close()
+		if err != nil {
+			return err
+		}
+		frames = append(frames, img)
+	}
+	outFile, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer outFile.Close()
+	return gif.EncodeAll(outFile, AnimateGIF(frames, delay))
+}
+
+func (t *Timelapse) framePaths() ([]string, error) {
+	listing, err := ioutil.ReadDir(t.Dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var res []string
+	for _, info := range listing {
+		name := info.Name()
+		if strings.HasPrefix(name, timelapseFramePrefix) && strings.HasSuffix(name, ".png") {
+			res = append(res, filepath.Join(t.Dir, name))
+		}
+	}
+	sort.Strings(res)
+	return res, nil
+}
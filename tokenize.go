@@ -0,0 +1,393 @@
+package gans
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/unixpickle/serializer"
+)
+
+// UnknownToken is the text of the symbol which WordVocab
+// and BPEVocab use for unrecognized input.
+const UnknownToken = "<unk>"
+
+func init() {
+	var w WordVocab
+	serializer.RegisterTypedDeserializer(w.SerializerType(), DeserializeWordVocab)
+	var b BPEVocab
+	serializer.RegisterTypedDeserializer(b.SerializerType(), DeserializeBPEVocab)
+}
+
+// WordTokens splits text into words and punctuation.
+// Words are runs of letters, digits, and apostrophes;
+// every other non-space character is its own token.
+func WordTokens(text string) []string {
+	var res []string
+	var word []rune
+	flush := func() {
+		if len(word) > 0 {
+			res = append(res, string(word))
+			word = word[:0]
+		}
+	}
+	for _, ch := range text {
+		if unicode.IsLetter(ch) || unicode.IsDigit(ch) || ch == '\'' {
+			word = append(word, ch)
+			continue
+		}
+		flush()
+		if !unicode.IsSpace(ch) {
+			res = append(res, string(ch))
+		}
+	}
+	flush()
+	return res
+}
+
+// WordVocab is a Vocabulary of words, as produced by
+// WordTokens.
+//
+// Symbol 0 is UnknownToken, which stands for every word
+// that is not in the vocabulary.
+type WordVocab struct {
+	words   []string
+	indices map[string]int
+}
+
+// NewWordVocab creates a WordVocab from a list of words.
+// UnknownToken is added as the first word.
+func NewWordVocab(words []string) *WordVocab {
+	res := &WordVocab{indices: map[string]int{}}
+	for _, word := range append([]string{UnknownToken}, words...) {
+		if _, ok := res.indices[word]; !ok {
+			res.indices[word] = len(res.words)
+			res.words = append(res.words, word)
+		}
+	}
+	return res
+}
+
+// BuildWordVocab creates a WordVocab with the most
+// common words in a list of sentences.
+// Words which appear fewer than minCount times are not
+// included.
+// If maxWords is non-zero, it limits the number of words
+// (not including UnknownToken).
+func BuildWordVocab(sentences []string, maxWords, minCount int) *WordVocab {
+	counts := map[string]int{}
+	for _, sentence := range sentences {
+		for _, word := range WordTokens(sentence) {
+			counts[word]++
+		}
+	}
+	var words []string
+	for word, count := range counts {
+		if count >= minCount && word != UnknownToken {
+			words = append(words, word)
+		}
+	}
+	sort.Slice(words, func(i, j int) bool {
+		if counts[words[i]] != counts[words[j]] {
+			return counts[words[i]] > counts[words[j]]
+		}
+		return words[i] < words[j]
+	})
+	if maxWords > 0 && len(words) > maxWords {
+		words = words[:maxWords]
+	}
+	return NewWordVocab(words)
+}
+
+// DeserializeWordVocab deserializes a WordVocab.
+func DeserializeWordVocab(d []byte) (*WordVocab, error) {
+	var words []string
+	if err := json.Unmarshal(d, &words); err != nil {
+		return nil, err
+	}
+	if len(words) == 0 || words[0] != UnknownToken {
+		return nil, errors.New("invalid word vocabulary")
+	}
+	return NewWordVocab(words[1:]), nil
+}
+
+// Words returns the words in the vocabulary, starting
+// with UnknownToken.
+func (w *WordVocab) Words() []string {
+	return append([]string{}, w.words...)
+}
+
+// Size returns the number of words, including
+// UnknownToken.
+func (w *WordVocab) Size() int {
+	return len(w.words)
+}
+
+// Encode converts text to word indices.
+// Unknown words are encoded as symbol 0, so encoding
+// always succeeds.
+func (w *WordVocab) Encode(text string) ([]int, bool) {
+	tokens := WordTokens(text)
+	res := make([]int, len(tokens))
+	for i, token := range tokens {
+		res[i] = w.indices[token]
+	}
+	return res, true
+}
+
+// Decode returns the word for a symbol.
+func (w *WordVocab) Decode(symbol int) string {
+	return w.words[symbol]
+}
+
+// DecodeSeq joins the words for a sequence of symbols,
+// putting spaces between words but not before
+// punctuation.
+func (w *WordVocab) DecodeSeq(symbols []int) string {
+	var res []string
+	for i, symbol := range symbols {
+		word := w.words[symbol]
+		if i > 0 && !isPunctuationToken(word) {
+			res = append(res, " ")
+		}
+		res = append(res, word)
+	}
+	return strings.Join(res, "")
+}
+
+// SerializerType returns the unique ID used to serialize
+// a WordVocab with the serializer package.
+func (w *WordVocab) SerializerType() string {
+	return "github.com/unixpickle/gans.WordVocab"
+}
+
+// Serialize serializes the vocabulary.
+func (w *WordVocab) Serialize() ([]byte, error) {
+	return json.Marshal(w.words)
+}
+
+// BPEVocab is a Vocabulary of subword tokens learned with
+// byte pair encoding.
+//
+// Text is split into pieces at spaces, with each space
+// kept at the start of the following piece, and tokens
+// never cross piece boundaries.
+// Decoding each symbol separately and concatenating the
+// results reproduces the original text.
+//
+// Symbol 0 is UnknownToken, which stands for characters
+// that were not seen during training.
+type BPEVocab struct {
+	// Tokens contains the text of every symbol.
+	Tokens []string
+
+	// Merges contains the pairs of tokens which are merged
+	// during encoding, in order of priority.
+	// The result of Merges[i] is Tokens[len(Tokens)-
+	// len(Merges)+i].
+	Merges [][2]int
+
+	indices map[string]int
+	ranks   map[[2]int]int
+}
+
+// TrainBPE learns a BPEVocab from a list of sentences.
+// Starting with every character in the sentences, it
+// repeatedly merges the most frequent pair of adjacent
+// tokens, until numMerges merges have been performed or
+// no pair occurs more than once.
+func TrainBPE(sentences []string, numMerges int) *BPEVocab {
+	pieceCounts := map[string]int{}
+	for _, sentence := range sentences {
+		for _, piece := range bpePieces(sentence) {
+			pieceCounts[piece]++
+		}
+	}
+
+	var sortedPieces []string
+	for piece := range pieceCounts {
+		sortedPieces = append(sortedPieces, piece)
+	}
+	sort.Strings(sortedPieces)
+
+	res := &BPEVocab{Tokens: []string{UnknownToken}}
+	res.index()
+	var pieces [][]int
+	var counts []int
+	for _, piece := range sortedPieces {
+		var symbols []int
+		for _, ch := range piece {
+			if _, ok := res.indices[string(ch)]; !ok {
+				res.indices[string(ch)] = len(res.Tokens)
+				res.Tokens = append(res.Tokens, string(ch))
+			}
+			symbols = append(symbols, res.indices[string(ch)])
+		}
+		pieces = append(pieces, symbols)
+		counts = append(counts, pieceCounts[piece])
+	}
+
+	for len(res.Merges) < numMerges {
+		pairCounts := map[[2]int]int{}
+		for i, piece := range pieces {
+			for j := 1; j < len(piece); j++ {
+				pairCounts[[2]int{piece[j-1], piece[j]}] += counts[i]
+			}
+		}
+		var best [2]int
+		var bestCount int
+		var bestText string
+		for pair, count := range pairCounts {
+			text := res.Tokens[pair[0]] + res.Tokens[pair[1]]
+			if count > bestCount || (count == bestCount && text < bestText) {
+				best, bestCount, bestText = pair, count, text
+			}
+		}
+		if bestCount < 2 {
+			break
+		}
+		merged := res.addMerge(best)
+		for i, piece := range pieces {
+			pieces[i] = mergePair(piece, best, merged)
+		}
+	}
+	return res
+}
+
+// DeserializeBPEVocab deserializes a BPEVocab.
+func DeserializeBPEVocab(d []byte) (*BPEVocab, error) {
+	var obj struct {
+		Tokens []string
+		Merges [][2]int
+	}
+	if err := json.Unmarshal(d, &obj); err != nil {
+		return nil, err
+	}
+	if len(obj.Tokens) == 0 || obj.Tokens[0] != UnknownToken ||
+		len(obj.Merges) >= len(obj.Tokens) {
+		return nil, errors.New("invalid BPE vocabulary")
+	}
+	for _, merge := range obj.Merges {
+		if merge[0] < 0 || merge[1] < 0 || merge[0] >= len(obj.Tokens) ||
+			merge[1] >= len(obj.Tokens) {
+			return nil, errors.New("invalid BPE vocabulary")
+		}
+	}
+	res := &BPEVocab{Tokens: obj.Tokens, Merges: obj.Merges}
+	res.index()
+	return res, nil
+}
+
+// Size returns the number of tokens.
+func (b *BPEVocab) Size() int {
+	return len(b.Tokens)
+}
+
+// Encode converts text to tokens.
+// Unknown characters are encoded as symbol 0, so encoding
+// always succeeds.
+func (b *BPEVocab) Encode(text string) ([]int, bool) {
+	if b.indices == nil {
+		b.index()
+	}
+	var res []int
+	for _, piece := range bpePieces(text) {
+		var symbols []int
+		for _, ch := range piece {
+			symbols = append(symbols, b.indices[string(ch)])
+		}
+		for len(symbols) > 1 {
+			bestRank := -1
+			for i := 1; i < len(symbols); i++ {
+				rank, ok := b.ranks[[2]int{symbols[i-1], symbols[i]}]
+				if ok && (bestRank == -1 || rank < bestRank) {
+					bestRank = rank
+				}
+			}
+			if bestRank == -1 {
+				break
+			}
+			merged := len(b.Tokens) - len(b.Merges) + bestRank
+			symbols = mergePair(symbols, b.Merges[bestRank], merged)
+		}
+		res = append(res, symbols...)
+	}
+	return res, true
+}
+
+// Decode returns the text of a token.
+func (b *BPEVocab) Decode(symbol int) string {
+	return b.Tokens[symbol]
+}
+
+// SerializerType returns the unique ID used to serialize
+// a BPEVocab with the serializer package.
+func (b *BPEVocab) SerializerType() string {
+	return "github.com/unixpickle/gans.BPEVocab"
+}
+
+// Serialize serializes the vocabulary.
+func (b *BPEVocab) Serialize() ([]byte, error) {
+	return json.Marshal(struct {
+		Tokens []string
+		Merges [][2]int
+	}{b.Tokens, b.Merges})
+}
+
+func (b *BPEVocab) index() {
+	b.indices = map[string]int{}
+	b.ranks = map[[2]int]int{}
+	firstMerged := len(b.Tokens) - len(b.Merges)
+	for i, token := range b.Tokens[:firstMerged] {
+		b.indices[token] = i
+	}
+	for i, merge := range b.Merges {
+		b.ranks[merge] = i
+	}
+}
+
+func (b *BPEVocab) addMerge(pair [2]int) int {
+	b.Tokens = append(b.Tokens, b.Tokens[pair[0]]+b.Tokens[pair[1]])
+	b.ranks[pair] = len(b.Merges)
+	b.Merges = append(b.Merges, pair)
+	return len(b.Tokens) - 1
+}
+
+// bpePieces splits text before every space.
+func bpePieces(text string) []string {
+	var res []string
+	start := 0
+	for i, ch := range text {
+		if ch == ' ' && i > start {
+			res = append(res, text[start:i])
+			start = i
+		}
+	}
+	if start < len(text) {
+		res = append(res, text[start:])
+	}
+	return res
+}
+
+// mergePair replaces every occurrence of a pair of
+// symbols with a merged symbol.
+func mergePair(symbols []int, pair [2]int, merged int) []int {
+	var res []int
+	for i := 0; i < len(symbols); i++ {
+		if i+1 < len(symbols) && symbols[i] == pair[0] && symbols[i+1] == pair[1] {
+			res = append(res, merged)
+			i++
+		} else {
+			res = append(res, symbols[i])
+		}
+	}
+	return res
+}
+
+func isPunctuationToken(token string) bool {
+	runes := []rune(token)
+	return len(runes) == 1 && !unicode.IsLetter(runes[0]) && !unicode.IsDigit(runes[0]) &&
+		runes[0] != '\''
+}
@@ -0,0 +1,46 @@
+package gans
+
+import "testing"
+
+func TestBPERoundTrip(t *testing.T) {
+	sentences := []string{
+		"the cat sat on the mat.",
+		"the dog sat on the log!",
+		"a cat and a dog.",
+	}
+	vocab := TrainBPE(sentences, 20)
+	if len(vocab.Merges) == 0 {
+		t.Fatal("no merges were learned")
+	}
+	data, err := vocab.Serialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := DeserializeBPEVocab(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range []*BPEVocab{vocab, loaded} {
+		for _, sentence := range sentences {
+			symbols, ok := v.Encode(sentence)
+			if !ok {
+				t.Errorf("failed to encode %q", sentence)
+				continue
+			}
+			if len(symbols) >= len(sentence) {
+				t.Errorf("encoding of %q was not compressed", sentence)
+			}
+			if decoded := DecodeText(v, symbols); decoded != sentence {
+				t.Errorf("expected %q but got %q", sentence, decoded)
+			}
+		}
+	}
+}
+
+func TestBPEUnknownCharacters(t *testing.T) {
+	vocab := TrainBPE([]string{"abc abc"}, 5)
+	symbols, _ := vocab.Encode("abz")
+	if len(symbols) == 0 || symbols[len(symbols)-1] != 0 {
+		t.Errorf("expected unknown symbol at end but got %v", symbols)
+	}
+}
@@ -0,0 +1,221 @@
+package gans
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/unixpickle/serializer"
+)
+
+func init() {
+	var b ByteVocab
+	serializer.RegisterTypedDeserializer(b.SerializerType(), DeserializeByteVocab)
+	var r RuneVocab
+	serializer.RegisterTypedDeserializer(r.SerializerType(), DeserializeRuneVocab)
+	var a AlphabetVocab
+	serializer.RegisterTypedDeserializer(a.SerializerType(), DeserializeAlphabetVocab)
+}
+
+// A Vocabulary maps between text and the symbols which
+// are fed to and produced by sequence models.
+//
+// Vocabularies are serializable so that they can be
+// saved with the models that use them.
+type Vocabulary interface {
+	serializer.Serializer
+
+	// Size returns the number of symbols, which is also
+	// the size of the one-hot vectors for the symbols.
+	Size() int
+
+	// Encode converts text to symbols.
+	// If the text cannot be represented, ok is false.
+	Encode(text string) (symbols []int, ok bool)
+
+	// Decode converts a symbol to text.
+	Decode(symbol int) string
+}
+
+// A SeqDecoder is a Vocabulary which needs to see entire
+// sequences to decode them, e.g. to put spaces between
+// words.
+type SeqDecoder interface {
+	DecodeSeq(symbols []int) string
+}
+
+// DecodeText converts a sequence of symbols to text.
+// If v is a SeqDecoder, its DecodeSeq method is used.
+// Otherwise, every symbol is decoded separately and the
+// results are concatenated.
+func DecodeText(v Vocabulary, symbols []int) string {
+	if d, ok := v.(SeqDecoder); ok {
+		return d.DecodeSeq(symbols)
+	}
+	var res strings.Builder
+	for _, symbol := range symbols {
+		res.WriteString(v.Decode(symbol))
+	}
+	return res.String()
+}
+
+// ByteVocab is a Vocabulary which maps every byte to a
+// symbol.
+// Decoded bytes are interpreted as Latin-1 characters,
+// as in ByteDecoder.
+type ByteVocab struct{}
+
+// DeserializeByteVocab deserializes a ByteVocab.
+func DeserializeByteVocab(d []byte) (ByteVocab, error) {
+	return ByteVocab{}, nil
+}
+
+// Size returns 256.
+func (b ByteVocab) Size() int {
+	return 256
+}
+
+// Encode returns the bytes of the text.
+func (b ByteVocab) Encode(text string) ([]int, bool) {
+	res := make([]int, len(text))
+	for i := 0; i < len(text); i++ {
+		res[i] = int(text[i])
+	}
+	return res, true
+}
+
+// Decode decodes a byte.
+func (b ByteVocab) Decode(symbol int) string {
+	return ByteDecoder(symbol)
+}
+
+// SerializerType returns the unique ID used to serialize
+// a ByteVocab with the serializer package.
+func (b ByteVocab) SerializerType() string {
+	return "github.com/unixpickle/gans.ByteVocab"
+}
+
+// Serialize serializes the vocabulary.
+func (b ByteVocab) Serialize() ([]byte, error) {
+	return []byte{}, nil
+}
+
+// RuneVocab is a Vocabulary which maps Unicode code
+// points to symbols.
+type RuneVocab struct {
+	// Limit is the number of code points in the
+	// vocabulary, starting from 0.
+	// Text with larger code points cannot be encoded.
+	Limit int
+}
+
+// DeserializeRuneVocab deserializes a RuneVocab.
+func DeserializeRuneVocab(d []byte) (RuneVocab, error) {
+	var limit serializer.Int
+	if err := serializer.DeserializeAny(d, &limit); err != nil {
+		return RuneVocab{}, err
+	}
+	return RuneVocab{Limit: int(limit)}, nil
+}
+
+// Size returns the limit.
+func (r RuneVocab) Size() int {
+	return r.Limit
+}
+
+// Encode returns the code points of the text.
+func (r RuneVocab) Encode(text string) ([]int, bool) {
+	var res []int
+	for _, ch := range text {
+		if int(ch) >= r.Limit {
+			return nil, false
+		}
+		res = append(res, int(ch))
+	}
+	return res, true
+}
+
+// Decode decodes a code point.
+func (r RuneVocab) Decode(symbol int) string {
+	return string(rune(symbol))
+}
+
+// SerializerType returns the unique ID used to serialize
+// a RuneVocab with the serializer package.
+func (r RuneVocab) SerializerType() string {
+	return "github.com/unixpickle/gans.RuneVocab"
+}
+
+// Serialize serializes the vocabulary.
+func (r RuneVocab) Serialize() ([]byte, error) {
+	return serializer.SerializeAny(serializer.Int(r.Limit))
+}
+
+// AlphabetVocab is a Vocabulary with a fixed set of
+// characters.
+type AlphabetVocab struct {
+	runes   []rune
+	indices map[rune]int
+}
+
+// NewAlphabetVocab creates an AlphabetVocab whose symbols
+// are the characters of alphabet, in order.
+// Duplicate characters map to their first occurrence.
+func NewAlphabetVocab(alphabet string) *AlphabetVocab {
+	res := &AlphabetVocab{indices: map[rune]int{}}
+	for _, ch := range alphabet {
+		if _, ok := res.indices[ch]; !ok {
+			res.indices[ch] = len(res.runes)
+			res.runes = append(res.runes, ch)
+		}
+	}
+	return res
+}
+
+// DeserializeAlphabetVocab deserializes an
+// AlphabetVocab.
+func DeserializeAlphabetVocab(d []byte) (*AlphabetVocab, error) {
+	res := NewAlphabetVocab(string(d))
+	if string(res.runes) != string(d) {
+		return nil, errors.New("invalid alphabet")
+	}
+	return res, nil
+}
+
+// Alphabet returns the characters in the vocabulary.
+func (a *AlphabetVocab) Alphabet() string {
+	return string(a.runes)
+}
+
+// Size returns the number of characters.
+func (a *AlphabetVocab) Size() int {
+	return len(a.runes)
+}
+
+// Encode returns the index of every character.
+func (a *AlphabetVocab) Encode(text string) ([]int, bool) {
+	var res []int
+	for _, ch := range text {
+		idx, ok := a.indices[ch]
+		if !ok {
+			return nil, false
+		}
+		res = append(res, idx)
+	}
+	return res, true
+}
+
+// Decode returns the character for a symbol.
+func (a *AlphabetVocab) Decode(symbol int) string {
+	return string(a.runes[symbol])
+}
+
+// SerializerType returns the unique ID used to serialize
+// an AlphabetVocab with the serializer package.
+func (a *AlphabetVocab) SerializerType() string {
+	return "github.com/unixpickle/gans.AlphabetVocab"
+}
+
+// Serialize serializes the vocabulary.
+func (a *AlphabetVocab) Serialize() ([]byte, error) {
+	return []byte(string(a.runes)), nil
+}